@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/app"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// newCleanupCmd creates the "cleanup" command and its plan/apply sub-commands
+func newCleanupCmd(cfg *config.Config) *cobra.Command {
+	cleanupCmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Review date-based cleanups before applying them",
+	}
+
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Write the items a cleanup would remove to a plan file",
+		Long: `Traverse the root folder and write the items selected for removal to a JSON plan file,
+with their IDs, paths, dates and the rule that selected each one. Nothing is changed in Google Drive.
+Review the plan, then run "cleanup apply" with it.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signalContext()
+			defer stop()
+
+			dropDefaultMatch(cmd, cfg)
+			if err := app.PlanCleanup(ctx, *cfg); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	planCmd.Flags().StringVar(&cfg.PlanOut, "out", "", "Path of the plan file to write (required)")
+	planCmd.Flags().StringVar(&cfg.RootFolderID, "root-folder-id", "", "ID of the root folder to clean up (required)")
+	addCleanupFlags(planCmd, cfg)
+	addParserFlags(planCmd, cfg)
+
+	applyCmd := &cobra.Command{
+		Use:   "apply <plan.json>",
+		Short: "Remove exactly the items of a plan file",
+		Long: `Remove the items listed in a plan file written by "cleanup plan", trashing them or, if the plan
+was made with --permanent, deleting them. Each item is re-checked first and skipped if it changed
+since the plan was made. A confirmation is requested unless --yes is given.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signalContext()
+			defer stop()
+
+			if err := app.ApplyCleanupPlan(ctx, *cfg, args[0]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	addSafetyFlags(applyCmd, cfg)
+
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Restore the items removed by a cleanup run from trash",
+		Long: `Restore from trash the items a cleanup run moved there, as recorded in its run log.
+The most recent run is undone unless --run is given. Items that were permanently deleted since are reported.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signalContext()
+			defer stop()
+
+			if err := app.UndoCleanup(ctx, *cfg); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	undoCmd.Flags().StringVar(&cfg.RunID, "run", "", "ID of the run to undo, as printed by the cleanup (default: the most recent run)")
+
+	cleanupCmd.PersistentFlags().StringVar(&cfg.RunLogDir, "run-log-dir", config.DefaultRunLogDir, "Directory where cleanup runs log the items they removed (empty disables the log)")
+	cleanupCmd.AddCommand(planCmd)
+	cleanupCmd.AddCommand(applyCmd)
+	cleanupCmd.AddCommand(undoCmd)
+	return cleanupCmd
+}
+
+// addCleanupFlags registers the retention and matching flags shared by --cleanup and "cleanup plan"
+func addCleanupFlags(cmd *cobra.Command, cfg *config.Config) {
+	cmd.Flags().IntVar(&cfg.Keep, "keep", 1, "Number of most recent date folders to keep (used with --cleanup)")
+	cmd.Flags().StringVar(&cfg.OlderThan, "older-than", "", "Trash date folders older than this age, e.g. 30d, 2w, 36h; --keep still applies as a floor (used with --cleanup)")
+	cmd.Flags().StringVar(&cfg.MaxSize, "max-size", "", "Byte budget per group of date folders, e.g. 200GB; the oldest are trashed until it fits, --keep still applies as a floor (used with --cleanup)")
+	cmd.Flags().IntVar(&cfg.KeepDaily, "keep-daily", 0, "Keep the newest date folder of each of the last N days (used with --cleanup)")
+	cmd.Flags().IntVar(&cfg.KeepWeekly, "keep-weekly", 0, "Keep the newest date folder of each of the last N ISO weeks (used with --cleanup)")
+	cmd.Flags().IntVar(&cfg.KeepMonthly, "keep-monthly", 0, "Keep the newest date folder of each of the last N months (used with --cleanup)")
+	cmd.Flags().IntVar(&cfg.KeepYearly, "keep-yearly", 0, "Keep the newest date folder of each of the last N years (used with --cleanup)")
+	cmd.Flags().BoolVar(&cfg.Permanent, "permanent", false, "Permanently delete expired items instead of moving them to trash (asks for confirmation unless --yes)")
+	cmd.Flags().StringVar(&cfg.Action, "action", "trash", "What to do with expired items: 'trash', or 'archive' to move them below --archive-folder-id keeping their path")
+	cmd.Flags().StringVar(&cfg.ArchiveFolderID, "archive-folder-id", "", "ID of the folder expired items are moved to with --action archive")
+	cmd.Flags().StringVar(&cfg.CleanupTarget, "cleanup-target", "folders", "What cleanup applies retention to: 'folders' (date-named folders) or 'files' (date-stamped files grouped by service)")
+	cmd.Flags().StringVar(&cfg.MatchPattern, "match", "yyyy-MM-dd", "Date pattern to match folder names (e.g., yyyy-MM-dd, yyyyMMdd, yyyy-'W'ww, 'snap_'yyyyMMdd_HHmmss)")
+	cmd.Flags().StringVar(&cfg.DateSource, "date-source", "name", "How items are dated: 'name' (parsed with --match), or the Drive 'created' or 'modified' time, with --match, --match-regex or --path-include narrowing the scope")
+	cmd.Flags().StringVar(&cfg.MatchRegex, "match-regex", "", "Regular expression with named groups year, month, day (and optionally hour, minute, second, week) used instead of --match")
+	cmd.Flags().IntVar(&cfg.Concurrency, "concurrency", 4, "Number of Drive requests made in parallel while traversing folders")
+	cmd.Flags().IntVar(&cfg.MaxDepth, "max-depth", 0, "Only examine folders up to N levels below the root (0 means no limit)")
+	cmd.Flags().StringSliceVar(&cfg.PathInclude, "path-include", nil, "Only clean up folders whose path below the root matches one of these globs, and their subfolders (e.g. 'SERVICE-*/db')")
+	cmd.Flags().StringSliceVar(&cfg.PathExclude, "path-exclude", nil, "Skip folders whose path below the root matches one of these globs, and their subfolders")
+	addSafetyFlags(cmd, cfg)
+}
+
+// dropDefaultMatch clears the default --match when items are dated by a Drive
+// timestamp, where --match is only a name filter and must be given explicitly
+func dropDefaultMatch(cmd *cobra.Command, cfg *config.Config) {
+	if cfg.DateSource != "" && cfg.DateSource != "name" && !cmd.Flags().Changed("match") {
+		cfg.MatchPattern = ""
+	}
+}
+
+// addSafetyFlags registers the limits that guard against removing too much in one cleanup
+func addSafetyFlags(cmd *cobra.Command, cfg *config.Config) {
+	cmd.Flags().IntVar(&cfg.MaxDelete, "max-delete", 0, "Abort without removing anything if the cleanup would remove more than N items (0 means no limit)")
+	cmd.Flags().StringVar(&cfg.MinAge, "min-age", "", "Never remove items dated or modified more recently than this, e.g. 7d")
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	// Embedded zone database for --timezone in images without tzdata (alpine)
+	_ "time/tzdata"
 
 	"github.com/eliasferreira/google-drive-uploader/internal/app"
 	"github.com/eliasferreira/google-drive-uploader/internal/config"
@@ -20,32 +25,58 @@ func main() {
 Supports large files, automatic folder organization, and resumable uploads.`,
 		Args: cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := app.Run(cfg, args); err != nil {
+			ctx, stop := signalContext()
+			defer stop()
+
+			dropDefaultMatch(cmd, &cfg)
+			if err := app.Run(ctx, cfg, args); err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
 		},
 	}
 
+	// Flags shared by all commands
+	rootCmd.PersistentFlags().StringVar(&cfg.ClientSecret, "client-secret", config.DefaultCredentialsFilesPath, "Path to the OAuth 2.0 client secret file. Required only for generates a new token (defaults to /etc/google-drive-uploader/client-secret.json)")
+	rootCmd.PersistentFlags().StringVar(&cfg.TokenPath, "token-path", config.DefaultTokenFilePath, "Path to the OAuth 2.0 token file (defaults to /etc/google-drive-uploader/token.json)")
+	rootCmd.PersistentFlags().StringVar(&cfg.TokenKeyFile, "token-key-file", "", "Path to a base64 32-byte key the token file is encrypted with (default: $GDU_TOKEN_KEY or $GDU_TOKEN_PASSPHRASE, if set)")
+	rootCmd.PersistentFlags().StringVar(&cfg.TokenHelper, "token-helper", "", "Command that prints the token JSON on 'get' and receives refreshed tokens on 'store', used instead of --token-path")
+	rootCmd.PersistentFlags().StringVar(&cfg.ServiceAccount, "service-account", "", "Path to a service account JSON key, used instead of the OAuth token (no token.json needed)")
+	rootCmd.PersistentFlags().StringVar(&cfg.Impersonate, "impersonate", "", "Workspace user the service account acts as, e.g. backup@example.com (requires domain-wide delegation)")
+	rootCmd.PersistentFlags().BoolVarP(&cfg.Yes, "yes", "y", false, "Skip confirmation prompts for destructive operations (required when not running in a terminal)")
+
 	// Flags
-	rootCmd.Flags().StringVar(&cfg.ClientSecret, "client-secret", config.DefaultCredentialsFilesPath, "Path to the OAuth 2.0 client secret file. Required only for generates a new token (defaults to /etc/google-drive-uploader/client-secret.json)")
-	rootCmd.Flags().StringVar(&cfg.TokenPath, "token-path", config.DefaultTokenFilePath, "Path to the OAuth 2.0 token file (defaults to /etc/google-drive-uploader/token.json)")
 	rootCmd.Flags().StringVar(&cfg.RootFolderID, "root-folder-id", "", "ID of the root folder to save the file (required unless --token-gen is used)")
 	rootCmd.Flags().StringVar(&cfg.FileName, "file-name", "", "Name of the file to save in Google Drive (optional, defaults to source filename). Note: Applied to ALL files if multiple.")
 	rootCmd.Flags().StringVar(&cfg.FolderName, "folder-name", "", "Name of the sub-folder to save the file in (optional)")
 	rootCmd.Flags().BoolVar(&cfg.SmartOrganize, "smart-organize", false, "Enable smart organization based on filename")
+	addParserFlags(rootCmd, &cfg)
+	addLayoutFlags(rootCmd, &cfg)
 	rootCmd.Flags().StringVar(&cfg.WorkDir, "workdir", "", "Path to the directory containing files to upload")
 	rootCmd.Flags().BoolVar(&cfg.DeleteOnSuccess, "delete-on-success", false, "Delete the file after successful upload")
 	rootCmd.Flags().BoolVar(&cfg.DeleteOnDone, "delete-on-done", false, "Delete the file after upload attempt (success or failure)")
 	rootCmd.Flags().BoolVar(&cfg.TokenGen, "token-gen", false, "Generate token only (skips upload). Requires --client-secret")
+	rootCmd.Flags().BoolVar(&cfg.DeviceFlow, "device-flow", false, "Authorize by entering a code on another device instead of a local browser, e.g. over SSH (limits access to files the uploader creates)")
+	rootCmd.Flags().DurationVar(&cfg.Timeout, "timeout", 0, "Maximum duration of the whole run, e.g. 2h (0 means no limit)")
+	rootCmd.Flags().DurationVar(&cfg.FileTimeout, "file-timeout", 0, "Maximum duration of a single file upload, e.g. 30m (0 means no limit)")
 
 	// Cleanup flags
 	rootCmd.Flags().BoolVar(&cfg.Cleanup, "cleanup", false, "Enable cleanup mode to remove old date-based folders")
-	rootCmd.Flags().IntVar(&cfg.Keep, "keep", 1, "Number of most recent date folders to keep (used with --cleanup)")
-	rootCmd.Flags().StringVar(&cfg.MatchPattern, "match", "yyyy-MM-dd", "Date pattern to match folder names (e.g., yyyy-MM-dd, yyyyMMdd)")
+	addCleanupFlags(rootCmd, &cfg)
+	rootCmd.Flags().StringVar(&cfg.RunLogDir, "run-log-dir", config.DefaultRunLogDir, "Directory where cleanup runs log the items they removed, for 'cleanup undo' (empty disables the log)")
+
+	rootCmd.AddCommand(newCleanupCmd(&cfg))
+	rootCmd.AddCommand(newTrashCmd(&cfg))
+	rootCmd.AddCommand(newParseCmd(&cfg))
+	rootCmd.AddCommand(newTokenCmd(&cfg))
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
+
+// signalContext returns a context cancelled on Ctrl-C or SIGTERM (e.g. Kubernetes pod shutdown)
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/app"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// newParseCmd creates the "parse" command, which tests filename parse rules
+func newParseCmd(cfg *config.Config) *cobra.Command {
+	parseCmd := &cobra.Command{
+		Use:   "parse <filename>...",
+		Short: "Show how filenames are parsed for smart organization",
+		Long: `Show which parse rule matches each filename, the service, date, time, env and host it extracts,
+and the folders --organize-layout places it in.
+Rules from --parse-rules are tried in order, then the built-in [service]_backup_[date]_[time] convention.
+Nothing is uploaded and no credentials are needed.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := app.ParseNames(*cfg, args); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	addParserFlags(parseCmd, cfg)
+	addLayoutFlags(parseCmd, cfg)
+
+	return parseCmd
+}
+
+// addParserFlags registers the flags of commands that parse filenames
+func addParserFlags(cmd *cobra.Command, cfg *config.Config) {
+	cmd.Flags().StringVar(&cfg.ParseRules, "parse-rules", config.DefaultParseRulesPath, "JSON file of filename parse rules, tried in order before the built-in convention (the default file is optional)")
+	cmd.Flags().StringVar(&cfg.ServiceCase, "service-case", "upper-snake", "How service folder names are written: 'upper-snake' (MY_APP), 'lower-snake' (my_app), 'kebab' (my-app) or 'preserve'")
+	cmd.Flags().StringToStringVar(&cfg.ServiceAliases, "service-alias", nil, "Canonical folder of a service name, matched case-insensitively, e.g. kc=KEYCLOAK (overrides service_aliases of the rules file)")
+	cmd.Flags().StringVar(&cfg.Timezone, "timezone", "", "IANA time zone the UTC times in filenames are converted to for date folders and cleanup, e.g. America/Sao_Paulo (default UTC)")
+}
+
+// addLayoutFlags registers the smart organize folder hierarchy flags
+func addLayoutFlags(cmd *cobra.Command, cfg *config.Config) {
+	cmd.Flags().StringVar(&cfg.OrganizeLayout, "organize-layout", "", "Folder hierarchy of smart organize, with the fields {service}, {env}, {host}, {date}, {time}, {yyyy}, {MM}, {dd}, {HH}, {mm} and {ss} (default \"{service}/{date}\")")
+	cmd.Flags().StringVar(&cfg.OrganizeFallback, "organize-fallback", "", "What to do with files no parse rule matches: 'none' (current folder), 'unsorted' (the rules file's unsorted_folder, default _unsorted), 'mtime' (a yyyy-MM-dd folder of their modification time) or 'reject' (default: the rules file's fallback, else none)")
+	cmd.Flags().StringToStringVar(&cfg.OrganizeDefaults, "organize-default", nil, "Value of a layout field the filename does not provide, e.g. env=shared (without one, such files fail)")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/app"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// newTokenCmd creates the "token" command and its sub-commands
+func newTokenCmd(cfg *config.Config) *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the OAuth token file",
+	}
+
+	encryptCmd := &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt the token file at rest",
+		Long: `Encrypt the token file (or the --token-helper token) in place with the key from --token-key-file, GDU_TOKEN_KEY or GDU_TOKEN_PASSPHRASE.
+A plaintext token is migrated to the encrypted format; an encrypted one is re-encrypted.
+Every later run needs the same key to read the token.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := app.EncryptToken(*cfg); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	tokenCmd.AddCommand(encryptCmd)
+	return tokenCmd
+}
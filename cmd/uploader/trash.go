@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/app"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// newTrashCmd creates the "trash" command and its sub-commands
+func newTrashCmd(cfg *config.Config) *cobra.Command {
+	trashCmd := &cobra.Command{
+		Use:   "trash",
+		Short: "Manage items in the Google Drive trash",
+	}
+
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently delete items in the trash",
+		Long: `Permanently delete items that cleanup runs moved to the Google Drive trash, freeing their storage quota.
+Only items recorded in the cleanup run logs are considered, unless --all is given to purge the whole trash
+of the account. The items to delete are listed and a confirmation is requested unless --yes is given.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, stop := signalContext()
+			defer stop()
+
+			if err := app.PurgeTrash(ctx, *cfg); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	purgeCmd.Flags().StringVar(&cfg.OlderThan, "older-than", "", "Only purge items trashed more than this long ago, e.g. 30d, 2w, 36h (default: regardless of age)")
+	purgeCmd.Flags().StringVar(&cfg.RunLogDir, "run-log-dir", config.DefaultRunLogDir, "Directory of the cleanup run logs, which list the items cleanup trashed")
+	purgeCmd.Flags().BoolVar(&cfg.PurgeAll, "all", false, "Purge every item in the account's trash, not only those trashed by cleanup")
+
+	trashCmd.AddCommand(purgeCmd)
+	return trashCmd
+}
@@ -2,32 +2,36 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 
 	"github.com/eliasferreira/google-drive-uploader/internal/auth"
-	"github.com/eliasferreira/google-drive-uploader/internal/cleanup"
 	"github.com/eliasferreira/google-drive-uploader/internal/config"
 	"github.com/eliasferreira/google-drive-uploader/internal/driveclient"
-	"github.com/eliasferreira/google-drive-uploader/internal/parser"
 )
 
-// Run executes the main application using the provided configuration
-func Run(cfg config.Config, args []string) error {
-	ctx := context.Background()
-
+// Run executes the main application using the provided configuration.
+// Cancelling ctx (e.g. on SIGINT/SIGTERM) stops the run after the in-flight request is aborted.
+func Run(ctx context.Context, cfg config.Config, args []string) error {
 	// 1. Validate Config
 	if err := cfg.Validate(args); err != nil {
 		return fmt.Errorf("configuration error: %w", err)
 	}
 
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
 	// 2. Authentication
-	authenticator := auth.NewAuthenticator(cfg)
-	client, err := authenticator.GetClient(ctx)
+	client, err := authenticate(ctx, cfg)
 	if err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
+		return err
 	}
 
 	// If in token generation mode, we are done
@@ -51,28 +55,39 @@ func Run(cfg config.Config, args []string) error {
 	return runUploads(ctx, svc, cfg, args)
 }
 
-func runCleanup(ctx context.Context, svc *driveclient.DriveService, cfg config.Config) error {
-	// Run cleanup
-	cleanupSvc := cleanup.NewCleanupService(svc, cfg.MatchPattern, cfg.Keep)
-	deletedPaths, err := cleanupSvc.Run(ctx, cfg.RootFolderID)
+// authenticate returns an authorized HTTP client for the configured credentials
+func authenticate(ctx context.Context, cfg config.Config) (*http.Client, error) {
+	authenticator := auth.NewAuthenticator(cfg)
+	client, err := authenticator.GetClient(ctx)
 	if err != nil {
-		return fmt.Errorf("cleanup failed: %w", err)
+		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
+	return client, nil
+}
 
-	// Log all deleted paths
-	if len(deletedPaths) > 0 {
-		fmt.Println("\n=== Deleted Folders ===")
-		for _, path := range deletedPaths {
-			fmt.Printf("  - %s\n", path)
-		}
-	} else {
-		fmt.Println("No folders were deleted.")
+// connect authenticates and creates the Drive service used by the sub-commands
+func connect(ctx context.Context, cfg config.Config) (*driveclient.DriveService, error) {
+	client, err := authenticate(ctx, cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	svc, err := driveclient.NewDriveService(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create drive service: %w", err)
+	}
+	return svc, nil
 }
 
-func runUploads(ctx context.Context, svc *driveclient.DriveService, cfg config.Config, args []string) error {
+func runUploads(ctx context.Context, svc driveclient.Service, cfg config.Config, args []string) error {
+	var org *organizer
+	if cfg.SmartOrganize {
+		var err error
+		if org, err = newOrganizer(cfg); err != nil {
+			return err
+		}
+	}
+
 	filesToProcess := args
 	if cfg.WorkDir != "" {
 		entries, err := os.ReadDir(cfg.WorkDir)
@@ -92,26 +107,94 @@ func runUploads(ctx context.Context, svc *driveclient.DriveService, cfg config.C
 		cfg.FileName = ""
 	}
 
-	// Process each file
-	for _, filePath := range filesToProcess {
-		processFile(ctx, svc, cfg, filePath)
+	report := uploadFiles(ctx, svc, cfg, org, filesToProcess)
+	report.print()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("upload interrupted: %w", err)
 	}
 
 	return nil
 }
 
-func processFile(ctx context.Context, svc *driveclient.DriveService, cfg config.Config, filePath string) {
+// uploadFiles processes each file in turn until ctx is done; the files not
+// reached by then are reported as pending
+func uploadFiles(ctx context.Context, svc driveclient.Service, cfg config.Config, org *organizer, files []string) uploadReport {
+	var report uploadReport
+	for i, filePath := range files {
+		if ctx.Err() != nil {
+			report.pending = files[i:]
+			break
+		}
+
+		if err := processFileWithTimeout(ctx, svc, cfg, org, filePath); err != nil {
+			log.Printf("Error: %v", err)
+			report.failed = append(report.failed, fmt.Sprintf("%s: %v", filePath, err))
+			continue
+		}
+		report.completed = append(report.completed, filePath)
+	}
+	return report
+}
+
+// uploadReport tracks the outcome of each file so an interrupted run can tell what was done
+type uploadReport struct {
+	completed []string
+	failed    []string
+	pending   []string
+}
+
+func (r uploadReport) print() {
+	fmt.Println("\n=== Upload Summary ===")
+	printSection("Completed", r.completed)
+	printSection("Failed", r.failed)
+	printSection("Not processed", r.pending)
+}
+
+func printSection(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("%s (%d):\n", title, len(items))
+	for _, item := range items {
+		fmt.Printf("  - %s\n", item)
+	}
+}
+
+// errFileTimeout is the cause of a context cancelled by --file-timeout, which
+// is a failed upload rather than an interrupted run
+var errFileTimeout = errors.New("file upload timed out")
+
+// processFileWithTimeout applies the per-file timeout, if any, to processFile
+func processFileWithTimeout(ctx context.Context, svc driveclient.Service, cfg config.Config, org *organizer, filePath string) error {
+	if cfg.FileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, cfg.FileTimeout, errFileTimeout)
+		defer cancel()
+	}
+	return processFile(ctx, svc, cfg, org, filePath)
+}
+
+// interrupted reports whether the run was stopped, by a signal or --timeout,
+// as opposed to the upload itself failing or exceeding --file-timeout
+func interrupted(ctx context.Context) bool {
+	return ctx.Err() != nil && !errors.Is(context.Cause(ctx), errFileTimeout)
+}
+
+// processFile uploads one file. org is nil unless smart organization is enabled.
+func processFile(ctx context.Context, svc driveclient.Service, cfg config.Config, org *organizer, filePath string) error {
 	fmt.Printf("\n--- Processing: %s ---\n", filePath)
 
 	// Basic validation
 	info, err := os.Stat(filePath)
 	if os.IsNotExist(err) {
-		log.Printf("Error: File '%s' does not exist. Skipping.", filePath)
-		return
+		return fmt.Errorf("file '%s' does not exist, skipping", filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to stat '%s': %w", filePath, err)
 	}
 	if info.IsDir() {
-		log.Printf("Error: '%s' is a directory. Skipping.", filePath)
-		return
+		return fmt.Errorf("'%s' is a directory, skipping", filePath)
 	}
 
 	// Determine Filename
@@ -127,55 +210,36 @@ func processFile(ctx context.Context, svc *driveclient.DriveService, cfg config.
 	if cfg.FolderName != "" {
 		id, err := svc.FindOrCreateFolder(ctx, cfg.FolderName, parentID)
 		if err != nil {
-			log.Printf("Failed to find or create folder '%s': %v. Skipping file.", cfg.FolderName, err)
-			return
+			return fmt.Errorf("failed to find or create folder '%s': %w", cfg.FolderName, err)
 		}
 		parentID = id
 	}
 
 	// 2. Smart Organization Logic
-	if cfg.SmartOrganize {
-		meta, err := parser.ParseFilename(targetFileName)
+	if org != nil {
+		id, err := org.folder(ctx, svc, parentID, targetFileName, info)
 		if err != nil {
-			fmt.Printf("Warning: Could not parse filename for smart organization: %v. Proceeding in current folder.\n", err)
-		} else {
-			fmt.Printf("Smart Organize: Service='%s', Date='%s'\n", meta.Service, meta.Date)
-
-			// Service Folder
-			sID, err := svc.FindOrCreateFolder(ctx, meta.Service, parentID)
-			if err != nil {
-				log.Printf("Failed to create Service folder: %v. Skipping file.", err)
-				return
-			}
-			parentID = sID
-
-			// Date Folder
-			dID, err := svc.FindOrCreateFolder(ctx, meta.Date, parentID)
-			if err != nil {
-				log.Printf("Failed to create Date folder: %v. Skipping file.", err)
-				return
-			}
-			parentID = dID
+			return err
 		}
+		parentID = id
 	}
 
 	// Upload
 	f, err := os.Open(filePath)
 	if err != nil {
-		log.Printf("Failed to open file: %v. Skipping.", err)
-		return
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
 
 	fmt.Printf("Uploading as '%s' to folder ID '%s'...\n", targetFileName, parentID)
 	file, err := svc.UploadFile(ctx, f, targetFileName, parentID)
 	if err != nil {
-		log.Printf("Upload failed: %v", err)
-		if cfg.DeleteOnDone {
+		// An interrupted upload was never really attempted to completion, so keep the local file
+		if cfg.DeleteOnDone && !interrupted(ctx) {
 			fmt.Printf("Removing file after failure: %s\n", filePath)
 			os.Remove(filePath)
 		}
-		return
+		return fmt.Errorf("upload failed: %w", err)
 	}
 
 	fmt.Printf("Success! ID: %s, Size: %d bytes\n", file.Id, file.Size)
@@ -187,4 +251,6 @@ func processFile(ctx context.Context, svc *driveclient.DriveService, cfg config.
 			log.Printf("Failed to remove file: %v", err)
 		}
 	}
+
+	return nil
 }
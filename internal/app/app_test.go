@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+// fakeUploader records uploads; upload, if set, runs inside each UploadFile call
+type fakeUploader struct {
+	uploaded []string
+	upload   func(ctx context.Context, filename string) error
+}
+
+func (f *fakeUploader) UploadFile(ctx context.Context, file io.Reader, filename string, parentID string) (*drive.File, error) {
+	if _, err := io.Copy(io.Discard, file); err != nil {
+		return nil, err
+	}
+	if f.upload != nil {
+		if err := f.upload(ctx, filename); err != nil {
+			return nil, err
+		}
+	}
+	f.uploaded = append(f.uploaded, filename)
+	return &drive.File{Id: "id-" + filename, Name: filename}, nil
+}
+
+func (f *fakeUploader) FindOrCreateFolder(ctx context.Context, name string, parentID string) (string, error) {
+	return parentID + "/" + name, nil
+}
+
+// blockUntilDone simulates an upload that only ends when its context does
+func blockUntilDone(ctx context.Context, filename string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func writeFiles(t *testing.T, names ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestUploadFiles_Cancelled(t *testing.T) {
+	files := writeFiles(t, "a.tar.gz", "b.tar.gz", "c.tar.gz")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The signal arrives while the first file is uploading, which still completes
+	fake := &fakeUploader{upload: func(ctx context.Context, filename string) error {
+		cancel()
+		return nil
+	}}
+
+	report := uploadFiles(ctx, fake, config.Config{RootFolderID: "root"}, nil, files)
+
+	if !reflect.DeepEqual(report.completed, files[:1]) {
+		t.Errorf("completed = %v, want %v", report.completed, files[:1])
+	}
+	if !reflect.DeepEqual(report.pending, files[1:]) {
+		t.Errorf("pending = %v, want %v", report.pending, files[1:])
+	}
+	if len(report.failed) != 0 {
+		t.Errorf("failed = %v, want none", report.failed)
+	}
+	if !reflect.DeepEqual(fake.uploaded, []string{"a.tar.gz"}) {
+		t.Errorf("uploaded = %v, want only the first file", fake.uploaded)
+	}
+}
+
+func TestRunUploads_Interrupted(t *testing.T) {
+	files := writeFiles(t, "a.tar.gz", "b.tar.gz")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeUploader{upload: func(ctx context.Context, filename string) error {
+		cancel()
+		return nil
+	}}
+
+	err := runUploads(ctx, fake, config.Config{RootFolderID: "root"}, files)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("runUploads() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestProcessFile_DeleteOnDone(t *testing.T) {
+	tests := []struct {
+		name        string
+		runTimeout  time.Duration
+		cancelRun   bool
+		fileTimeout time.Duration
+		upload      func(ctx context.Context, filename string) error
+		wantKept    bool
+	}{
+		{
+			name:     "Upload error removes file",
+			upload:   func(ctx context.Context, filename string) error { return errors.New("backend error") },
+			wantKept: false,
+		},
+		{
+			name:        "File timeout removes file",
+			fileTimeout: 20 * time.Millisecond,
+			upload:      blockUntilDone,
+			wantKept:    false,
+		},
+		{
+			name:       "Run timeout keeps file",
+			runTimeout: 20 * time.Millisecond,
+			upload:     blockUntilDone,
+			wantKept:   true,
+		},
+		{
+			name:        "Run timeout before file timeout keeps file",
+			runTimeout:  20 * time.Millisecond,
+			fileTimeout: time.Hour,
+			upload:      blockUntilDone,
+			wantKept:    true,
+		},
+		{
+			name:      "Cancelled run keeps file",
+			cancelRun: true,
+			upload:    blockUntilDone,
+			wantKept:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeFiles(t, "backup.tar.gz")[0]
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.runTimeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, tt.runTimeout)
+				defer cancel()
+			}
+			if tt.cancelRun {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+
+			cfg := config.Config{RootFolderID: "root", DeleteOnDone: true, FileTimeout: tt.fileTimeout}
+			if err := processFileWithTimeout(ctx, &fakeUploader{upload: tt.upload}, cfg, nil, file); err == nil {
+				t.Fatal("processFileWithTimeout() error = nil, want upload error")
+			}
+
+			_, err := os.Stat(file)
+			if kept := err == nil; kept != tt.wantKept {
+				t.Errorf("file kept = %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
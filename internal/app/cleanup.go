@@ -0,0 +1,288 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/cleanup"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+	"github.com/eliasferreira/google-drive-uploader/internal/driveclient"
+)
+
+func runCleanup(ctx context.Context, svc *driveclient.DriveService, cfg config.Config) error {
+	if cfg.Permanent {
+		ok, err := confirm("Cleanup will permanently delete expired items. They cannot be restored from trash.", cfg.Yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Cleanup aborted.")
+			return nil
+		}
+	}
+
+	// Run cleanup
+	cleanupSvc, err := newCleanupService(svc, cfg)
+	if err != nil {
+		return err
+	}
+	deletedPaths, err := cleanupSvc.Run(ctx, cfg.RootFolderID)
+
+	// Log all deleted paths, including those trashed before an interruption
+	printDeleted(cfg.CleanupTarget, cfg.Action, deletedPaths)
+	saveRunLog(cfg, cleanupSvc)
+
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+
+	return nil
+}
+
+// PlanCleanup runs the cleanup traversal without changing anything and writes
+// the items it would remove to cfg.PlanOut, for review before ApplyCleanupPlan
+func PlanCleanup(ctx context.Context, cfg config.Config) error {
+	if err := cfg.ValidateCleanupPlan(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	svc, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	cleanupSvc, err := newCleanupService(svc, cfg)
+	if err != nil {
+		return err
+	}
+	plan, err := cleanupSvc.Plan(ctx, cfg.RootFolderID)
+	if err != nil {
+		return fmt.Errorf("cleanup plan failed: %w", err)
+	}
+
+	if err := cleanup.SavePlan(cfg.PlanOut, plan); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nPlanned %d %s for removal, written to: %s\n", len(plan.Items), plan.Target, cfg.PlanOut)
+	if len(plan.Errors) > 0 {
+		printSection("Warning: folders that could not be examined", plan.Errors)
+	}
+	return nil
+}
+
+// ApplyCleanupPlan removes exactly the items of a plan written by PlanCleanup.
+// Each item is re-checked first and skipped if it changed since the plan was made.
+func ApplyCleanupPlan(ctx context.Context, cfg config.Config, planPath string) error {
+	if err := cfg.ValidateCleanupApply(planPath); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	plan, err := cleanup.LoadPlan(planPath)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Items) == 0 {
+		fmt.Println("Nothing to apply, the plan is empty.")
+		return nil
+	}
+
+	// Check the safety limits before the user is asked to approve the plan
+	if err := cleanup.CheckMaxDelete(plan, cfg.MaxDelete); err != nil {
+		return err
+	}
+	minAge, err := parseMinAge(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Plan created at %s for root folder %s (pattern: %s, retention: %s)\n",
+		plan.CreatedAt.Format(time.RFC3339), plan.RootFolderID, plan.Pattern, plan.Retention)
+	fmt.Printf("The following %d %s will be %s:\n", len(plan.Items), plan.Target, plan.Outcome())
+	for _, item := range plan.Items {
+		fmt.Printf("  - %s (%s)\n", item.Path, item.Rule)
+	}
+
+	message := "Items that changed since the plan was made will be skipped."
+	if plan.Permanent {
+		message = "They cannot be restored from trash. " + message
+	}
+	ok, err := confirm(message, cfg.Yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Cleanup aborted.")
+		return nil
+	}
+
+	svc, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	cleanupSvc := cleanup.NewCleanupService(svc, cleanup.Options{
+		Target:     plan.Target,
+		DateSource: plan.DateSource,
+		MaxDelete:  cfg.MaxDelete,
+		MinAge:     minAge,
+	})
+	deletedPaths, err := cleanupSvc.Apply(ctx, plan, true)
+
+	printDeleted(plan.Target, plan.Action, deletedPaths)
+	saveRunLog(cfg, cleanupSvc)
+
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+
+	return nil
+}
+
+// UndoCleanup restores from trash the items removed by a logged cleanup run,
+// the most recent one unless cfg.RunID is set
+func UndoCleanup(ctx context.Context, cfg config.Config) error {
+	if err := cfg.ValidateCleanupUndo(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	run, err := cleanup.LoadRunLog(cfg.RunLogDir, cfg.RunID)
+	if err != nil {
+		return err
+	}
+
+	svc, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Undoing cleanup run %s (%d items)\n", run.ID, len(run.Items))
+	result, err := cleanup.NewCleanupService(svc, cleanup.Options{}).Undo(ctx, run)
+
+	fmt.Println("\n=== Undo Summary ===")
+	printSection("Restored", result.Restored)
+	printSection("Permanently deleted, cannot be restored", result.Missing)
+	printSection("Not in trash", result.Skipped)
+	printSection("Failed", result.Failed)
+
+	if err != nil {
+		return fmt.Errorf("undo failed: %w", err)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d of %d items could not be restored, run undo again to retry them", len(result.Failed), len(run.Items))
+	}
+
+	return nil
+}
+
+// saveRunLog logs the items removed by the last cleanup run so it can be undone.
+// The cleanup already happened, so failing to save the log is only a warning.
+func saveRunLog(cfg config.Config, cleanupSvc *cleanup.CleanupService) {
+	run := cleanupSvc.LastRun()
+	if run == nil || len(run.Items) == 0 || cfg.RunLogDir == "" {
+		return
+	}
+
+	path, err := cleanup.SaveRunLog(cfg.RunLogDir, run)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+
+	if run.Permanent || run.Action == cleanup.ActionArchive {
+		fmt.Printf("Run log saved to: %s\n", path)
+		return
+	}
+	fmt.Printf("Run log saved to: %s (undo with: uploader cleanup undo --run %s)\n", path, run.ID)
+}
+
+// newCleanupService creates a cleanup service from the cleanup flags
+func newCleanupService(svc *driveclient.DriveService, cfg config.Config) (*cleanup.CleanupService, error) {
+	var olderThan time.Duration
+	if cfg.OlderThan != "" {
+		var err error
+		if olderThan, err = config.ParseAge(cfg.OlderThan); err != nil {
+			return nil, fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+
+	var maxSize int64
+	if cfg.MaxSize != "" {
+		var err error
+		if maxSize, err = config.ParseSize(cfg.MaxSize); err != nil {
+			return nil, fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+
+	minAge, err := parseMinAge(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := newParser(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cleanup.NewCleanupService(svc, cleanup.Options{
+		DatePattern: cfg.MatchPattern,
+		MatchRegex:  cfg.MatchRegex,
+		Target:      cfg.CleanupTarget,
+		DateSource:  cfg.DateSource,
+		Retention: cleanup.RetentionPolicy{
+			Keep:        cfg.Keep,
+			KeepDaily:   cfg.KeepDaily,
+			KeepWeekly:  cfg.KeepWeekly,
+			KeepMonthly: cfg.KeepMonthly,
+			KeepYearly:  cfg.KeepYearly,
+			OlderThan:   olderThan,
+			MaxSize:     maxSize,
+		},
+		Permanent:       cfg.Permanent,
+		Action:          cfg.Action,
+		ArchiveFolderID: cfg.ArchiveFolderID,
+		MaxDelete:       cfg.MaxDelete,
+		MinAge:          minAge,
+		Concurrency:     cfg.Concurrency,
+		MaxDepth:        cfg.MaxDepth,
+		PathInclude:     cfg.PathInclude,
+		PathExclude:     cfg.PathExclude,
+		Parser:          names,
+	}), nil
+}
+
+// parseMinAge parses the --min-age safety limit
+func parseMinAge(cfg config.Config) (time.Duration, error) {
+	if cfg.MinAge == "" {
+		return 0, nil
+	}
+	minAge, err := config.ParseAge(cfg.MinAge)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --min-age: %w", err)
+	}
+	return minAge, nil
+}
+
+// printDeleted lists the paths removed (or archived) by a cleanup
+func printDeleted(target string, action string, deletedPaths []string) {
+	kind := "Folders"
+	if target == cleanup.TargetFiles {
+		kind = "Files"
+	}
+	verb := "Deleted"
+	if action == cleanup.ActionArchive {
+		verb = "Archived"
+	}
+
+	if len(deletedPaths) > 0 {
+		fmt.Printf("\n=== %s %s ===\n", verb, kind)
+		for _, path := range deletedPaths {
+			fmt.Printf("  - %s\n", path)
+		}
+	} else {
+		fmt.Printf("No %s were %s.\n", strings.ToLower(kind), strings.ToLower(verb))
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/cleanup"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+func TestApplyCleanupPlan_SafetyLimits(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token.json")
+	os.WriteFile(tokenPath, []byte(`{}`), 0600)
+	planPath := filepath.Join(dir, "plan.json")
+	plan := &cleanup.Plan{Version: 1, Items: []cleanup.PlanItem{
+		{ID: "d1", Path: "2025-01-01", Rule: cleanup.ReasonExpired},
+		{ID: "d2", Path: "2025-01-02", Rule: cleanup.ReasonExpired},
+		{ID: "d3", Path: "2025-01-03", Rule: cleanup.ReasonExpired},
+	}}
+	if err := cleanup.SavePlan(planPath, plan); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{"Max delete exceeded", config.Config{TokenPath: tokenPath, MaxDelete: 2}, "more than the limit of 2"},
+		{"Invalid min age", config.Config{TokenPath: tokenPath, MinAge: "soon"}, "--min-age"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without --yes and a terminal, asking for confirmation would fail
+			// with another error, so these must be checked first
+			err := ApplyCleanupPlan(context.Background(), tt.cfg, planPath)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ApplyCleanupPlan() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
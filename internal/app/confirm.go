@@ -0,0 +1,31 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// confirm asks the user to confirm a destructive operation.
+// It returns true without prompting when assumeYes is set (--yes), and fails
+// instead of blocking when stdin is not a terminal.
+func confirm(message string, assumeYes bool) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false, fmt.Errorf("confirmation required but stdin is not a terminal, use --yes to run non-interactively")
+	}
+
+	fmt.Printf("%s\nContinue? [y/N]: ", message)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("unable to read confirmation: %v", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+	"github.com/eliasferreira/google-drive-uploader/internal/driveclient"
+	"github.com/eliasferreira/google-drive-uploader/internal/parser"
+)
+
+// organizer places uploads in the folder hierarchy smart organize derives from their names
+type organizer struct {
+	names  *parser.Parser
+	layout *parser.Layout
+	// fallback is what happens to files no rule matches (a parser.Fallback constant)
+	fallback string
+	unsorted string
+}
+
+// newOrganizer loads the parse rules and the --organize-layout template.
+// --organize-fallback overrides the fallback of the rules file.
+func newOrganizer(cfg config.Config) (*organizer, error) {
+	set, err := loadRuleSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	names, err := compileParser(cfg, set)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := newLayout(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	org := &organizer{
+		names:    names,
+		layout:   layout,
+		fallback: set.Fallback,
+		unsorted: set.UnsortedFolder,
+	}
+	if cfg.OrganizeFallback != "" {
+		org.fallback = cfg.OrganizeFallback
+	}
+	if org.fallback == "" {
+		org.fallback = parser.FallbackNone
+	}
+	if org.unsorted == "" {
+		org.unsorted = parser.DefaultUnsortedFolder
+	}
+	return org, nil
+}
+
+// newLayout compiles --organize-layout and its --organize-default values
+func newLayout(cfg config.Config) (*parser.Layout, error) {
+	template := cfg.OrganizeLayout
+	if template == "" {
+		template = parser.DefaultLayout
+	}
+
+	layout, err := parser.ParseLayout(template, cfg.OrganizeDefaults)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --organize-layout: %w", err)
+	}
+	return layout, nil
+}
+
+// folder returns the ID of the folder below parentID where fileName belongs, creating
+// the hierarchy as needed. Names no rule matches are handled by the fallback.
+func (o *organizer) folder(ctx context.Context, svc driveclient.Service, parentID string, fileName string, info os.FileInfo) (string, error) {
+	meta, rule, err := o.names.Match(fileName)
+	if err != nil {
+		folders, err := o.fallbackFolders(err, info)
+		if err != nil {
+			return "", err
+		}
+		return createFolders(ctx, svc, parentID, folders)
+	}
+	fmt.Printf("Smart Organize: Service='%s', Date='%s' (rule '%s')\n", meta.Service, meta.Date, rule.Name)
+
+	folders, err := o.layout.Folders(meta)
+	if err != nil {
+		return "", fmt.Errorf("smart organization failed: %w", err)
+	}
+	return createFolders(ctx, svc, parentID, folders)
+}
+
+// fallbackFolders returns the folders of a file no rule matches, or an error if it is rejected
+func (o *organizer) fallbackFolders(parseErr error, info os.FileInfo) ([]string, error) {
+	switch o.fallback {
+	case parser.FallbackReject:
+		return nil, fmt.Errorf("rejected by smart organization: %w", parseErr)
+	case parser.FallbackUnsorted:
+		fmt.Printf("Warning: Could not parse filename for smart organization: %v. Proceeding in '%s'.\n", parseErr, o.unsorted)
+		return []string{o.unsorted}, nil
+	case parser.FallbackModTime:
+		date := info.ModTime().In(o.names.Location()).Format("2006-01-02")
+		fmt.Printf("Warning: Could not parse filename for smart organization: %v. Proceeding in '%s' (modification date).\n", parseErr, date)
+		return []string{date}, nil
+	default:
+		fmt.Printf("Warning: Could not parse filename for smart organization: %v. Proceeding in current folder.\n", parseErr)
+		return nil, nil
+	}
+}
+
+// fallbackDescription tells where files no rule matches go, for "uploader parse"
+func (o *organizer) fallbackDescription() string {
+	switch o.fallback {
+	case parser.FallbackReject:
+		return "rejected"
+	case parser.FallbackUnsorted:
+		return fmt.Sprintf("uploaded to '%s'", o.unsorted)
+	case parser.FallbackModTime:
+		return "uploaded to a folder named after its modification date"
+	default:
+		return "uploaded to the current folder"
+	}
+}
+
+// createFolders finds or creates the nested folders below parentID and returns the ID of the last one
+func createFolders(ctx context.Context, svc driveclient.Service, parentID string, folders []string) (string, error) {
+	for _, name := range folders {
+		id, err := svc.FindOrCreateFolder(ctx, name, parentID)
+		if err != nil {
+			return "", fmt.Errorf("failed to create folder '%s': %w", name, err)
+		}
+		parentID = id
+	}
+	return parentID, nil
+}
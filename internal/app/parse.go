@@ -0,0 +1,114 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+	"github.com/eliasferreira/google-drive-uploader/internal/parser"
+)
+
+// ParseNames shows which parse rule matches each filename, the fields it
+// extracts and its smart organize folders, to test --parse-rules and
+// --organize-layout without uploading anything
+func ParseNames(cfg config.Config, names []string) error {
+	if err := cfg.ValidateParseRules(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	org, err := newOrganizer(cfg)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, name := range names {
+		fmt.Println(name)
+		meta, rule, err := org.names.Match(name)
+		if err != nil {
+			fmt.Printf("  no rule matched, %s\n", org.fallbackDescription())
+			if org.fallback == parser.FallbackReject {
+				failed++
+			}
+			continue
+		}
+
+		fmt.Printf("  rule:    %s\n", rule.Name)
+		fmt.Printf("  service: %s\n", meta.Service)
+		fmt.Printf("  date:    %s\n", meta.Date)
+		if meta.Time != "" {
+			fmt.Printf("  time:    %s (%s)\n", meta.Time, meta.Timestamp.Format("MST"))
+		}
+		if meta.Env != "" {
+			fmt.Printf("  env:     %s\n", meta.Env)
+		}
+		if meta.Host != "" {
+			fmt.Printf("  host:    %s\n", meta.Host)
+		}
+
+		folders, err := org.layout.Folders(meta)
+		if err != nil {
+			fmt.Printf("  folders: %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("  folders: %s\n", strings.Join(folders, "/"))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d filenames could not be organized", failed, len(names))
+	}
+	return nil
+}
+
+// newParser loads the --parse-rules file and --timezone. The default rules
+// file is optional, so without it only the built-in convention is used.
+func newParser(cfg config.Config) (*parser.Parser, error) {
+	set, err := loadRuleSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return compileParser(cfg, set)
+}
+
+// loadRuleSet reads --parse-rules, or returns an empty rule set when the default file does not exist
+func loadRuleSet(cfg config.Config) (*parser.RuleSet, error) {
+	if cfg.ParseRules == "" {
+		return &parser.RuleSet{}, nil
+	}
+	if _, err := os.Stat(cfg.ParseRules); err != nil && cfg.ParseRules == config.DefaultParseRulesPath {
+		return &parser.RuleSet{}, nil
+	}
+	return parser.LoadRuleSet(cfg.ParseRules)
+}
+
+// compileParser compiles the rules of set for --timezone and --service-case.
+// --service-alias entries override the aliases of the rules file.
+func compileParser(cfg config.Config, set *parser.RuleSet) (*parser.Parser, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --timezone: %w", err)
+	}
+
+	// Keys are lower-cased first, so that kc=... on the command line replaces
+	// KC of the rules file instead of both matching
+	aliases := make(map[string]string)
+	for alias, canonical := range set.ServiceAliases {
+		aliases[strings.ToLower(alias)] = canonical
+	}
+	for alias, canonical := range cfg.ServiceAliases {
+		aliases[strings.ToLower(alias)] = canonical
+	}
+
+	p, err := parser.NewParser(set.Rules, parser.Options{
+		Location:       loc,
+		ServiceCase:    cfg.ServiceCase,
+		ServiceAliases: aliases,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid --parse-rules: %w", err)
+	}
+	return p, nil
+}
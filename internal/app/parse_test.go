@@ -0,0 +1,33 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+	"github.com/eliasferreira/google-drive-uploader/internal/parser"
+)
+
+func TestCompileParser_AliasOverride(t *testing.T) {
+	set := &parser.RuleSet{ServiceAliases: map[string]string{"KC": "KEYCLOAK", "oauth": "OAUTH"}}
+	cfg := config.Config{
+		Timezone:       "UTC",
+		ServiceAliases: map[string]string{"kc": "IAM"},
+	}
+
+	// Both spellings of kc are one alias, so the CLI wins on every run
+	for i := 0; i < 20; i++ {
+		p, err := compileParser(cfg, set)
+		if err != nil {
+			t.Fatalf("compileParser() error = %v", err)
+		}
+		for name, want := range map[string]string{"Kc": "IAM", "OAuth": "OAUTH"} {
+			meta, err := p.Parse(name + "_backup_20251102_040000.zip")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if meta.Service != want {
+				t.Fatalf("Parse(%s) service = %s, want %s", name, meta.Service, want)
+			}
+		}
+	}
+}
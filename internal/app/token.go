@@ -0,0 +1,27 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/auth"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+// EncryptToken encrypts the token (file or helper) in place with the configured token key,
+// migrating a legacy plaintext token
+func EncryptToken(cfg config.Config) error {
+	if err := cfg.ValidateTokenEncrypt(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	key, err := auth.LoadTokenKey(cfg.TokenKeyFile)
+	if err != nil {
+		return err
+	}
+	if err := auth.EncryptToken(cfg, key); err != nil {
+		return fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	fmt.Printf("Token encrypted with %s.\n", key)
+	return nil
+}
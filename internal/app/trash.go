@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/cleanup"
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+	"github.com/eliasferreira/google-drive-uploader/internal/trash"
+)
+
+// PurgeTrash permanently deletes the items cleanup runs moved to the Drive trash
+// (the whole trash with --all), optionally only those trashed more than --older-than ago
+func PurgeTrash(ctx context.Context, cfg config.Config) error {
+	if err := cfg.ValidateTrashPurge(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	var olderThan time.Duration
+	if cfg.OlderThan != "" {
+		var err error
+		if olderThan, err = config.ParseAge(cfg.OlderThan); err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+
+	// Without --all, only what cleanup runs trashed is purged; the logs also
+	// tell when My Drive items were trashed, which Drive does not report
+	trashed, err := cleanup.TrashedItems(cfg.RunLogDir)
+	if err != nil {
+		return err
+	}
+
+	svc, err := connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	purgeSvc := trash.NewPurgeService(svc, trash.Options{
+		OlderThan: olderThan,
+		Trashed:   trashed,
+		All:       cfg.PurgeAll,
+	})
+	candidates, err := purgeSvc.Candidates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("Nothing to purge.")
+		if !cfg.PurgeAll {
+			fmt.Printf("Only items trashed by the cleanup runs logged in %s are purged, use --all for the whole trash.\n", cfg.RunLogDir)
+		}
+		return nil
+	}
+
+	fmt.Printf("The following %d items will be permanently deleted:\n", len(candidates))
+	for _, f := range candidates {
+		fmt.Printf("  - %s\n", purgeSvc.Describe(f))
+	}
+
+	ok, err := confirm("This cannot be undone.", cfg.Yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Purge aborted.")
+		return nil
+	}
+
+	purged, err := purgeSvc.Purge(ctx, candidates)
+	fmt.Printf("\nPermanently deleted %d of %d items.\n", len(purged), len(candidates))
+	if err != nil {
+		return fmt.Errorf("purge failed: %w", err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -15,6 +16,9 @@ import (
 // Authenticator handles the OAuth2 authentication process
 type Authenticator struct {
 	Config config.Config
+
+	// store holds the OAuth token: the token file, GDU_TOKEN_JSON or a token helper
+	store tokenStore
 }
 
 // NewAuthenticator creates a new Authenticator
@@ -26,13 +30,28 @@ func NewAuthenticator(config config.Config) *Authenticator {
 
 // GetClient retrieves a token, saves the token, then returns the generated client.
 func (a *Authenticator) GetClient(ctx context.Context, scope ...string) (*http.Client, error) {
-	// If no scope is provided, default to DriveScope (full access)
+	// If no scope is provided, default to DriveScope (full access), or the
+	// narrower scope Google allows for device codes
 	if len(scope) == 0 {
 		scope = []string{"https://www.googleapis.com/auth/drive"}
+		if a.Config.DeviceFlow {
+			scope = []string{DeviceFlowScope}
+		}
+	}
+
+	// Service accounts need neither a token file nor a client secret
+	if a.Config.ServiceAccount != "" {
+		return a.getServiceAccountClient(ctx, scope...)
 	}
 
+	tokenKey, err := LoadTokenKey(a.Config.TokenKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	a.store = newTokenStore(a.Config, tokenKey)
+
 	// Check if we have a valid token first
-	tok, tokenFile, err := tokenFromFile(a.Config.TokenPath)
+	tok, tokenFile, err := a.store.Load()
 	if err != nil && !a.Config.TokenGen {
 		return nil, err
 	}
@@ -48,8 +67,7 @@ func (a *Authenticator) GetClient(ctx context.Context, scope ...string) (*http.C
 				Scopes:       scope,
 				Endpoint:     google.Endpoint,
 			}
-			client := a.getClient(ctx, config, tok)
-			return client, nil
+			return a.getClient(ctx, config, tok)
 		}
 	}
 
@@ -63,28 +81,36 @@ func (a *Authenticator) GetClient(ctx context.Context, scope ...string) (*http.C
 		return nil, fmt.Errorf("unable to read client secret file: %v\nPlease provide a valid client secret file by using --client-secret", err)
 	}
 
-	config, err := google.ConfigFromJSON(b, scope...)
+	var config *oauth2.Config
+	if a.Config.DeviceFlow {
+		config, err = deviceConfigFromJSON(b, scope...)
+	} else {
+		config, err = google.ConfigFromJSON(b, scope...)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
 
-	client := a.getClient(ctx, config, nil)
-	return client, nil
+	return a.getClient(ctx, config, nil)
 }
 
 // getClient retrieves a token, saves the token, then returns the generated client.
-func (a *Authenticator) getClient(ctx context.Context, config *oauth2.Config, existingToken *oauth2.Token) *http.Client {
+func (a *Authenticator) getClient(ctx context.Context, config *oauth2.Config, existingToken *oauth2.Token) (*http.Client, error) {
 	var tok *oauth2.Token
 
 	if existingToken != nil {
 		tok = existingToken
 	} else {
-		t, tokenData, err := tokenFromFile(a.Config.TokenPath)
+		t, _, err := a.store.Load()
 		if err != nil {
-			fmt.Printf("No token found at %s, starting authorization flow...\n", a.Config.TokenPath)
-			tok = getTokenFromWeb(config)
-			tokenData = NewTokenFile(config.ClientID, config.ClientSecret)
-			saveToken(a.Config.TokenPath, tokenData.Refresh(tok))
+			fmt.Printf("No token found in %s, starting authorization flow...\n", a.store)
+			tok, err = a.authorize(ctx, config)
+			if err != nil {
+				return nil, err
+			}
+			if err := a.saveAuthorized(config, tok); err != nil {
+				return nil, err
+			}
 		} else {
 			tok = t
 		}
@@ -93,24 +119,65 @@ func (a *Authenticator) getClient(ctx context.Context, config *oauth2.Config, ex
 	// Create a token source that will automatically refresh and save the token
 	ts := config.TokenSource(ctx, tok)
 	wrappedTs := &savingTokenSource{
-		source: ts,
-		path:   a.Config.TokenPath,
-		config: config,
+		source:  ts,
+		store:   a.store,
+		config:  config,
+		current: tok,
 	}
 
 	// Check if token is expired or will expire soon and refresh immediately if so
 	// This ensures we have a valid token before we start any operation
 	initialTok, err := wrappedTs.Token()
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// The token is fine, authorizing again would not help
+		if errors.Is(err, errTokenNotSaved) {
+			return nil, err
+		}
 		fmt.Printf("Failed to refresh token: %v. Requesting new authorization...\n", err)
-		tok = getTokenFromWeb(config)
-		tokenData := NewTokenFile(config.ClientID, config.ClientSecret)
-		saveToken(a.Config.TokenPath, tokenData.Refresh(tok))
+		tok, err = a.authorize(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.saveAuthorized(config, tok); err != nil {
+			return nil, err
+		}
 		// Update the wrapped source with the new token
 		ts = config.TokenSource(ctx, tok)
 		wrappedTs.source = ts
+		wrappedTs.current = tok
 		initialTok = tok
 	}
 
-	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(initialTok, wrappedTs))
+	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(initialTok, wrappedTs)), nil
+}
+
+// saveAuthorized saves a newly authorized token under the store lock, so it
+// is not interleaved with the refresh of another run sharing the store. The
+// lock is only taken once the user is done, not while they authorize, and a
+// lock that cannot be taken does not keep the token from being saved.
+func (a *Authenticator) saveAuthorized(config *oauth2.Config, tok *oauth2.Token) error {
+	unlock, err := a.store.Lock()
+	if err != nil {
+		// Losing the new authorization would be worse than an unserialized write
+		fmt.Printf("Warning: unable to lock %s, saving without the lock: %v\n", a.store, err)
+		unlock = func() {}
+	}
+	defer unlock()
+
+	tokenData := NewTokenFile(config.ClientID, config.ClientSecret)
+	if err := a.store.Save(tokenData.Refresh(tok)); err != nil {
+		return fmt.Errorf("%w: %v", errTokenNotSaved, err)
+	}
+	return nil
+}
+
+// authorize asks the user for a new token, with the device flow if --device-flow is set
+func (a *Authenticator) authorize(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
+	if a.Config.DeviceFlow {
+		return getTokenFromDevice(ctx, config)
+	}
+	return getTokenFromWeb(ctx, config)
 }
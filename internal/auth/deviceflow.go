@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// DeviceFlowScope is the Drive scope requested with the device flow. Google
+// only allows drive.file (files created or opened by the app) for device codes.
+const DeviceFlowScope = "https://www.googleapis.com/auth/drive.file"
+
+// deviceConfigFromJSON reads the client ID and secret of a client secret file.
+// Clients of type "TVs and Limited Input devices" have no redirect URIs, which
+// google.ConfigFromJSON requires, so the file is parsed here.
+func deviceConfigFromJSON(b []byte, scope ...string) (*oauth2.Config, error) {
+	type credentials struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	var file struct {
+		Web       *credentials `json:"web"`
+		Installed *credentials `json:"installed"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, err
+	}
+
+	c := file.Installed
+	if c == nil {
+		c = file.Web
+	}
+	if c == nil || c.ClientID == "" {
+		return nil, fmt.Errorf("no client credentials found")
+	}
+
+	return &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Scopes:       scope,
+		Endpoint:     google.Endpoint,
+	}, nil
+}
+
+// getTokenFromDevice runs the OAuth device authorization grant: the user code
+// is entered at the verification URL from any browser, while this process polls
+// for the token. Returns ctx.Err() if the context is cancelled while waiting.
+func getTokenFromDevice(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
+	resp, err := config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start device authorization: %v", err)
+	}
+
+	fmt.Printf("To authorize, visit:\n%s\n", resp.VerificationURI)
+	fmt.Printf("and enter the code: %s\n\n", resp.UserCode)
+	if !resp.Expiry.IsZero() {
+		fmt.Printf("Waiting for authorization (the code expires at %s)...\n", resp.Expiry.Local().Format("15:04:05"))
+	}
+
+	tok, err := config.DeviceAccessToken(ctx, resp)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("device authorization failed: %v", err)
+	}
+
+	fmt.Println("Authorization received!")
+	return tok, nil
+}
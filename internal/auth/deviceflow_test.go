@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestDeviceConfigFromJSON(t *testing.T) {
+	// Limited input device clients have no redirect URIs
+	config, err := deviceConfigFromJSON([]byte(`{"installed": {"client_id": "device-client", "client_secret": "device-secret"}}`), DeviceFlowScope)
+	if err != nil {
+		t.Fatalf("deviceConfigFromJSON() error = %v", err)
+	}
+	if config.ClientID != "device-client" || config.ClientSecret != "device-secret" {
+		t.Errorf("credentials = %q/%q", config.ClientID, config.ClientSecret)
+	}
+	if config.Endpoint.DeviceAuthURL == "" {
+		t.Error("Endpoint.DeviceAuthURL is empty")
+	}
+
+	if _, err := deviceConfigFromJSON([]byte(`{}`)); err == nil {
+		t.Error("deviceConfigFromJSON() expected an error without credentials")
+	}
+}
+
+func TestGetTokenFromDevice(t *testing.T) {
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device/code", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"device_code": "dev-123", "user_code": "ABCD-EFGH", "verification_url": "https://www.google.com/device", "expires_in": 60, "interval": 1}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("device_code") != "dev-123" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant"}`))
+			return
+		}
+		// The user has not entered the code on the first poll
+		if polls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "authorization_pending"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "device-access", "refresh_token": "device-refresh", "token_type": "Bearer", "expires_in": 3600}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	config := &oauth2.Config{
+		ClientID:     "device-client",
+		ClientSecret: "device-secret",
+		Scopes:       []string{DeviceFlowScope},
+		Endpoint: oauth2.Endpoint{
+			DeviceAuthURL: server.URL + "/device/code",
+			TokenURL:      server.URL + "/token",
+		},
+	}
+
+	tok, err := getTokenFromDevice(context.Background(), config)
+	if err != nil {
+		t.Fatalf("getTokenFromDevice() error = %v", err)
+	}
+	if tok.AccessToken != "device-access" || tok.RefreshToken != "device-refresh" {
+		t.Errorf("token = %q/%q", tok.AccessToken, tok.RefreshToken)
+	}
+	if polls.Load() != 2 {
+		t.Errorf("polled %d times, want 2", polls.Load())
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package auth
+
+// lockFile is a no-op where flock is not available; token writes are still atomic
+func lockFile(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -2,8 +2,8 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -11,13 +11,14 @@ import (
 
 // Request a token from the web, then returns the retrieved token.
 // Uses local callback server with automatic browser opening, falls back to manual flow if needed.
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
+// Returns ctx.Err() if the context is cancelled while waiting for authorization.
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
 	// Try to use callback server approach
 	port, err := findAvailablePort()
 	if err != nil {
 		fmt.Printf("Warning: Could not find available port: %v\n", err)
 		fmt.Println("Falling back to manual authorization flow...")
-		return getTokenFromWebManual(config)
+		return getTokenFromWebManual(ctx, config)
 	}
 
 	// Update config to use local callback
@@ -27,8 +28,15 @@ func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
 	}
 	config.RedirectURL = fmt.Sprintf("http://localhost:%d/callback", port)
 
+	// A random state ties the callback to this request, and PKCE ties the code to this process
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
 	// Start callback server
-	codeChan, errChan, server := startCallbackServer(port)
+	codeChan, errChan, server := startCallbackServer(port, state)
 	defer func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -39,7 +47,7 @@ func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
 	time.Sleep(100 * time.Millisecond)
 
 	// Generate auth URL
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
 
 	// Try to open browser
 	fmt.Printf("Opening browser for authorization...\n")
@@ -57,69 +65,126 @@ func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
 		authCode = code
 		fmt.Println("Authorization code received!")
 	case err := <-errChan:
+		// The user or Google refused, so the manual flow would fail too
+		if errors.Is(err, errAccessDenied) || errors.Is(err, errAuthorizationFailed) {
+			return nil, err
+		}
 		fmt.Printf("Error from callback server: %v\n", err)
 		fmt.Println("Falling back to manual authorization flow...")
 		config.RedirectURL = originalRedirectURL
-		return getTokenFromWebManual(config)
+		return getTokenFromWebManual(ctx, config)
 	case <-time.After(5 * time.Minute):
 		fmt.Println("Timeout waiting for authorization. Falling back to manual flow...")
 		config.RedirectURL = originalRedirectURL
-		return getTokenFromWebManual(config)
+		return getTokenFromWebManual(ctx, config)
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
 	// Exchange code for token
-	tok, err := config.Exchange(context.Background(), authCode)
+	tok, err := config.Exchange(ctx, authCode, oauth2.VerifierOption(verifier))
 	if err != nil {
-		fmt.Printf("Unable to retrieve token from web: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to retrieve token from web: %v", err)
 	}
 
-	return tok
+	return tok, nil
 }
 
 // getTokenFromWebManual is the fallback manual authorization flow
-func getTokenFromWebManual(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+func getTokenFromWebManual(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
+	// The code is pasted by the user, so there is no callback to check the state against
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
 	fmt.Printf("Go to the following link in your browser then type the authorization code: \n%v\n", authURL)
 
+	// Read in the background so a cancelled context doesn't wait for stdin
+	codeChan := make(chan string, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		var authCode string
+		if _, err := fmt.Scan(&authCode); err != nil {
+			errChan <- err
+			return
+		}
+		codeChan <- authCode
+	}()
+
 	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		fmt.Printf("Unable to read authorization code: %v", err)
-		os.Exit(1)
+	select {
+	case authCode = <-codeChan:
+	case err := <-errChan:
+		return nil, fmt.Errorf("unable to read authorization code: %v", err)
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
-	tok, err := config.Exchange(context.TODO(), authCode)
+	tok, err := config.Exchange(ctx, authCode, oauth2.VerifierOption(verifier))
 	if err != nil {
-		fmt.Printf("Unable to retrieve token from web: %v", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to retrieve token from web: %v", err)
 	}
-	return tok
+	return tok, nil
 }
 
-// savingTokenSource wraps an oauth2.TokenSource to save the token whenever it is refreshed.
+// errTokenNotSaved marks a token that was obtained but could not be stored, so
+// callers do not mistake it for a failed refresh
+var errTokenNotSaved = errors.New("unable to save token")
+
+// savingTokenSource wraps an oauth2.TokenSource to save the token to its store whenever it is refreshed.
 type savingTokenSource struct {
 	source oauth2.TokenSource
-	path   string
+	store  tokenStore
 	config *oauth2.Config
+	// current is the token source returns until it expires, nil if unknown
+	current *oauth2.Token
 }
 
 func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	// The source returns a valid token as is: there is nothing to refresh or save
+	if s.current.Valid() {
+		return s.current, nil
+	}
+
+	// Hold the store lock across refresh, read, compare and write, so two runs
+	// refreshing at once do not interleave their writes
+	unlock, err := s.store.Lock()
+	if err != nil {
+		// e.g. no permission to create the lock file: the refreshed token is
+		// still good for this run, and the stored refresh token for the next
+		fmt.Printf("Warning: unable to lock %s, the refreshed token is not saved: %v\n", s.store, err)
+		tok, err := s.source.Token()
+		if err != nil {
+			return nil, err
+		}
+		s.current = tok
+		return tok, nil
+	}
+	defer unlock()
+
 	tok, err := s.source.Token()
 	if err != nil {
 		return nil, err
 	}
+	s.current = tok
 
-	// We only want to save if the token was actually refreshed.
-	// oauth2.TokenSource might return the same token if it's still valid.
-	// However, config.TokenSource often manages this.
-	// To be safe and simple, we can load the current file and compare,
-	// or just check if the expiry or access token is different from what we might have.
-	// But since we want to be sure it's always up to date:
-
-	current, tokenData, _ := tokenFromFile(s.path)
+	// Only save when the token was actually refreshed: the source returns the
+	// same token while it is still valid
+	current, tokenData, _ := s.store.Load()
 	if current == nil || current.AccessToken != tok.AccessToken || !current.Expiry.Equal(tok.Expiry) {
-		fmt.Printf("Token refreshed, saving to %s\n", s.path)
-		saveToken(s.path, tokenData.Refresh(tok))
+		if tokenData == nil {
+			tokenData = NewTokenFile(s.config.ClientID, s.config.ClientSecret)
+		}
+		fmt.Printf("Token refreshed, saving to %s\n", s.store)
+		if err := s.store.Save(tokenData.Refresh(tok)); err != nil {
+			if !errors.Is(err, errReadOnlyStore) {
+				return nil, fmt.Errorf("%w: %v", errTokenNotSaved, err)
+			}
+			fmt.Printf("Note: %v\n", err)
+		}
 	}
 
 	return tok, nil
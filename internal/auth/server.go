@@ -1,9 +1,14 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 )
 
 // findAvailablePort finds an available port starting from 54321
@@ -19,21 +24,77 @@ func findAvailablePort() (int, error) {
 	return 0, fmt.Errorf("no available ports found in range 8080-8099")
 }
 
-// startCallbackServer starts a local HTTP server to handle OAuth callback
-func startCallbackServer(port int) (chan string, chan error, *http.Server) {
+// Errors the authorization server reports in the callback
+var (
+	// errAccessDenied is reported when the user declines the authorization
+	errAccessDenied = errors.New("authorization was denied in the browser")
+	// errAuthorizationFailed is reported for any other error parameter
+	errAuthorizationFailed = errors.New("authorization failed")
+)
+
+// startCallbackServer starts a local HTTP server to handle OAuth callback.
+// Callbacks whose state does not match are rejected without affecting the
+// flow, so another page or local process cannot inject an authorization code.
+// Only the first valid callback is delivered, on codeChan or errChan; a
+// repeated one, e.g. from a reloaded page, is answered without blocking.
+func startCallbackServer(port int, state string) (chan string, chan error, *http.Server) {
 	codeChan := make(chan string, 1)
 	errChan := make(chan error, 1)
 
+	var once sync.Once
+	deliver := func(code string, err error) bool {
+		delivered := false
+		once.Do(func() {
+			if err != nil {
+				errChan <- err
+			} else {
+				codeChan <- code
+			}
+			delivered = true
+		})
+		return delivered
+	}
+	const alreadyDone = "Authorization was already completed. You can close this window."
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
+		query := r.URL.Query()
+		if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+			http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+			return
+		}
+
+		if authErr := query.Get("error"); authErr != "" {
+			if authErr == "access_denied" {
+				if !deliver("", errAccessDenied) {
+					http.Error(w, alreadyDone, http.StatusConflict)
+					return
+				}
+				http.Error(w, "Authorization was denied. You can close this window.", http.StatusForbidden)
+				return
+			}
+			if !deliver("", fmt.Errorf("%w: %s", errAuthorizationFailed, authErr)) {
+				http.Error(w, alreadyDone, http.StatusConflict)
+				return
+			}
+			http.Error(w, "Authorization failed: "+authErr, http.StatusBadRequest)
+			return
+		}
+
+		code := query.Get("code")
 		if code == "" {
-			errChan <- fmt.Errorf("no authorization code in callback")
+			if !deliver("", fmt.Errorf("no authorization code in callback")) {
+				http.Error(w, alreadyDone, http.StatusConflict)
+				return
+			}
 			http.Error(w, "No authorization code received", http.StatusBadRequest)
 			return
 		}
 
-		codeChan <- code
+		if !deliver(code, nil) {
+			http.Error(w, alreadyDone, http.StatusConflict)
+			return
+		}
 
 		// Display success page
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -63,9 +124,18 @@ func startCallbackServer(port int) (chan string, chan error, *http.Server) {
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			errChan <- fmt.Errorf("callback server error: %v", err)
+			deliver("", fmt.Errorf("callback server error: %v", err))
 		}
 	}()
 
 	return codeChan, errChan, server
 }
+
+// randomState returns an unguessable OAuth state parameter
+func randomState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate state: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
@@ -18,29 +19,30 @@ func TestFindAvailablePort(t *testing.T) {
 	}
 }
 
-func TestCallbackServerHandler(t *testing.T) {
-	// Pick a port - we don't start the real server but we test the handler logic
-	// Actually startCallbackServer returns the server and channels, we can test it fully if we want.
-	// But let's test the handler primarily via httptest for better isolation?
-	// The handler logic is inside startCallbackServer closure.
-	// Ideally we would extract the handler func, but since it's private and inside,
-	// let's test the full integration of startCallbackServer.
-
+// startTestCallbackServer starts a callback server on a free port and returns
+// its callback URL; each server delivers one result, so each case starts one
+func startTestCallbackServer(t *testing.T, state string) (string, chan string, chan error) {
+	t.Helper()
 	port, err := findAvailablePort()
 	if err != nil {
 		t.Fatal("Could not find port for test")
 	}
 
-	codeChan, errChan, server := startCallbackServer(port)
-	defer server.Close() // Will be closed by shutdown usually but good for test cleanup
+	codeChan, errChan, server := startCallbackServer(port, state)
+	t.Cleanup(func() { server.Close() })
 
 	// Wait a bit for server to start
 	time.Sleep(100 * time.Millisecond)
 
-	baseURL := fmt.Sprintf("http://localhost:%d/callback", port)
+	return fmt.Sprintf("http://localhost:%d/callback", port), codeChan, errChan
+}
+
+func TestCallbackServerHandler(t *testing.T) {
+	const state = "test-state"
 
 	t.Run("Success Case", func(t *testing.T) {
-		resp, err := http.Get(baseURL + "?code=test-auth-code")
+		baseURL, codeChan, _ := startTestCallbackServer(t, state)
+		resp, err := http.Get(baseURL + "?code=test-auth-code&state=" + state)
 		if err != nil {
 			t.Fatalf("Failed to make request: %v", err)
 		}
@@ -67,14 +69,8 @@ func TestCallbackServerHandler(t *testing.T) {
 	})
 
 	t.Run("Missing Code", func(t *testing.T) {
-		// Drain channels first
-		select {
-		case <-codeChan:
-		case <-errChan:
-		default:
-		}
-
-		resp, err := http.Get(baseURL) // No code param
+		baseURL, _, errChan := startTestCallbackServer(t, state)
+		resp, err := http.Get(baseURL + "?state=" + state) // No code param
 		if err != nil {
 			t.Fatalf("Failed to make request: %v", err)
 		}
@@ -93,4 +89,126 @@ func TestCallbackServerHandler(t *testing.T) {
 			t.Error("Timeout waiting for error on channel")
 		}
 	})
+	t.Run("Invalid State", func(t *testing.T) {
+		baseURL, codeChan, errChan := startTestCallbackServer(t, state)
+		for _, query := range []string{"?code=injected-code", "?code=injected-code&state=other-state"} {
+			resp, err := http.Get(baseURL + query)
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %s, got %d", query, resp.StatusCode)
+			}
+		}
+
+		// A forged callback must neither deliver a code nor abort the flow
+		select {
+		case code := <-codeChan:
+			t.Errorf("Unexpected code on channel: %s", code)
+		case err := <-errChan:
+			t.Errorf("Unexpected error on channel: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("Repeated Callback", func(t *testing.T) {
+		baseURL, codeChan, errChan := startTestCallbackServer(t, state)
+
+		// Nothing reads the channels, so a blocking send would hang the second request
+		client := &http.Client{Timeout: 2 * time.Second}
+		for i, query := range []string{
+			"?code=first-code&state=" + state,
+			"?code=second-code&state=" + state,
+			"?error=access_denied&state=" + state,
+		} {
+			resp, err := client.Get(baseURL + query)
+			if err != nil {
+				t.Fatalf("Failed to make request %d: %v", i+1, err)
+			}
+			resp.Body.Close()
+
+			want := http.StatusConflict
+			if i == 0 {
+				want = http.StatusOK
+			}
+			if resp.StatusCode != want {
+				t.Errorf("Expected status %d for %s, got %d", want, query, resp.StatusCode)
+			}
+		}
+
+		if code := <-codeChan; code != "first-code" {
+			t.Errorf("Expected code 'first-code', got '%s'", code)
+		}
+		select {
+		case code := <-codeChan:
+			t.Errorf("Unexpected second code on channel: %s", code)
+		case err := <-errChan:
+			t.Errorf("Unexpected error on channel: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("Access Denied", func(t *testing.T) {
+		baseURL, _, errChan := startTestCallbackServer(t, state)
+		resp, err := http.Get(baseURL + "?error=access_denied&state=" + state)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status 403 for access_denied, got %d", resp.StatusCode)
+		}
+
+		select {
+		case err := <-errChan:
+			if !errors.Is(err, errAccessDenied) {
+				t.Errorf("Expected errAccessDenied, got %v", err)
+			}
+		case <-time.After(1 * time.Second):
+			t.Error("Timeout waiting for error on channel")
+		}
+	})
+
+	t.Run("Other Authorization Error", func(t *testing.T) {
+		baseURL, _, errChan := startTestCallbackServer(t, state)
+		resp, err := http.Get(baseURL + "?error=invalid_scope&state=" + state)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid_scope, got %d", resp.StatusCode)
+		}
+
+		select {
+		case err := <-errChan:
+			if !errors.Is(err, errAuthorizationFailed) {
+				t.Errorf("Expected errAuthorizationFailed, got %v", err)
+			}
+		case <-time.After(1 * time.Second):
+			t.Error("Timeout waiting for error on channel")
+		}
+	})
+}
+
+func TestRandomState(t *testing.T) {
+	first, err := randomState()
+	if err != nil {
+		t.Fatalf("randomState() error = %v", err)
+	}
+	second, err := randomState()
+	if err != nil {
+		t.Fatalf("randomState() error = %v", err)
+	}
+
+	if first == second {
+		t.Error("randomState() returned the same state twice")
+	}
+	if len(first) < 43 {
+		t.Errorf("randomState() = %q, want at least 32 random bytes", first)
+	}
 }
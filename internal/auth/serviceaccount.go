@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
+)
+
+// serviceAccountConfig builds the JWT config of a service account JSON key.
+// When impersonate is set, the service account acts as that Workspace user,
+// which requires domain-wide delegation for the scopes.
+func serviceAccountConfig(keyPath string, impersonate string, scope ...string) (*jwt.Config, error) {
+	b, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account key: %v", err)
+	}
+
+	config, err := google.JWTConfigFromJSON(b, scope...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse service account key: %v", err)
+	}
+	config.Subject = impersonate
+	return config, nil
+}
+
+// getServiceAccountClient returns a client authorized as the service account.
+// Its tokens are short-lived and signed locally, so nothing is saved.
+func (a *Authenticator) getServiceAccountClient(ctx context.Context, scope ...string) (*http.Client, error) {
+	config, err := serviceAccountConfig(a.Config.ServiceAccount, a.Config.Impersonate, scope...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail now on an invalid key or missing delegation rather than on the first request
+	if _, err := config.TokenSource(ctx).Token(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("unable to authorize service account %s: %v", config.Email, err)
+	}
+
+	return config.Client(ctx), nil
+}
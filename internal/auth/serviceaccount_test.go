@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+// writeServiceAccountKey writes a service account key whose tokens are requested from tokenURL
+func writeServiceAccountKey(t *testing.T, tokenURL string) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "uploader@project.iam.gserviceaccount.com",
+		"private_key_id": "key-1",
+		"private_key":    string(pemKey),
+		"token_uri":      tokenURL,
+	})
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestServiceAccountConfig(t *testing.T) {
+	path := writeServiceAccountKey(t, "https://oauth2.googleapis.com/token")
+
+	cfg, err := serviceAccountConfig(path, "backup@example.com", "https://www.googleapis.com/auth/drive")
+	if err != nil {
+		t.Fatalf("serviceAccountConfig() error = %v", err)
+	}
+	if cfg.Email != "uploader@project.iam.gserviceaccount.com" {
+		t.Errorf("Email = %q", cfg.Email)
+	}
+	if cfg.Subject != "backup@example.com" {
+		t.Errorf("Subject = %q, want backup@example.com", cfg.Subject)
+	}
+
+	if _, err := serviceAccountConfig(filepath.Join(t.TempDir(), "missing.json"), ""); err == nil {
+		t.Error("serviceAccountConfig() expected an error for a missing key")
+	}
+}
+
+func TestGetServiceAccountClient(t *testing.T) {
+	var subject string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The JWT assertion carries the impersonated user as "sub"
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) == 3 {
+			payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			var claims struct {
+				Sub string `json:"sub"`
+			}
+			json.Unmarshal(payload, &claims)
+			subject = claims.Sub
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "sa-token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	authenticator := NewAuthenticator(config.Config{
+		ServiceAccount: writeServiceAccountKey(t, tokenServer.URL),
+		Impersonate:    "backup@example.com",
+		TokenPath:      filepath.Join(t.TempDir(), "token.json"),
+	})
+
+	client, err := authenticator.GetClient(context.Background())
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+	if client == nil {
+		t.Fatal("GetClient() returned a nil client")
+	}
+	if subject != "backup@example.com" {
+		t.Errorf("assertion sub = %q, want backup@example.com", subject)
+	}
+	if _, err := os.Stat(authenticator.Config.TokenPath); !os.IsNotExist(err) {
+		t.Errorf("service account mode must not write a token file")
+	}
+}
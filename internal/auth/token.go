@@ -1,8 +1,8 @@
 package auth
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -10,6 +10,9 @@ import (
 	"golang.org/x/oauth2"
 )
 
+// tokenBackupSuffix is appended to the token path for the copy of the previous token
+const tokenBackupSuffix = ".bak"
+
 // TokenFile represents the enhanced token format with embedded client credentials
 // This allows token refresh without requiring the client-secret.json file
 type TokenFile struct {
@@ -35,8 +38,9 @@ func (t *TokenFile) Refresh(token *oauth2.Token) *TokenFile {
 }
 
 // Retrieves a token from a local file.
-// Supports both enhanced TokenFile format and standard oauth2.Token format
-func tokenFromFile(file string) (*oauth2.Token, *TokenFile, error) {
+// Supports both enhanced TokenFile format and standard oauth2.Token format,
+// encrypted with key or as legacy plaintext
+func tokenFromFile(file string, key *TokenKey) (*oauth2.Token, *TokenFile, error) {
 	f, err := os.Open(file)
 	if err != nil {
 		return nil, nil, err
@@ -52,9 +56,17 @@ func tokenFromFile(file string) (*oauth2.Token, *TokenFile, error) {
 		return nil, nil, fmt.Errorf("token path '%s' is a directory, expected a file", file)
 	}
 
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokenFromJSON(data, key)
+}
+
+// tokenFromJSON decodes a token, encrypted with key or as legacy plaintext
+func tokenFromJSON(data []byte, key *TokenKey) (*oauth2.Token, *TokenFile, error) {
 	// Try to read as TokenFile first (enhanced format)
-	tokenFile := &TokenFile{}
-	err = json.NewDecoder(f).Decode(tokenFile)
+	tokenFile, err := decodeTokenFile(data, key)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -70,23 +82,69 @@ func tokenFromFile(file string) (*oauth2.Token, *TokenFile, error) {
 	return tok, tokenFile, nil
 }
 
-// Saves a token to a file path with optional OAuth config for enhanced format
-func saveToken(path string, token *TokenFile) {
+// Saves a token to a file path, encrypted when key is set. The token is
+// written to a temporary file that replaces the previous one, which is kept as
+// a .bak backup, so a crash or a concurrent reader never sees a partial token.
+func saveToken(path string, token *TokenFile, key *TokenKey) error {
 	fmt.Printf("Saving credential file to: %s\n", path)
+	data, err := encodeTokenFile(token, key)
+	if err != nil {
+		return fmt.Errorf("unable to encrypt oauth token: %v", err)
+	}
+
 	// Ensure directory exists
-	if dir := filepath.Dir(path); dir != "" {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			fmt.Printf("Unable to create directory for token: %v\n", err)
-			return
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("unable to create directory for token: %w", err)
+	}
+
+	// Keep the previous token in case the new one turns out to be unusable
+	if previous, err := os.ReadFile(path); err == nil {
+		if backup, ok := tokenBackup(previous, key); ok {
+			if err := writeFileAtomic(path+tokenBackupSuffix, backup); err != nil {
+				return fmt.Errorf("unable to back up previous token: %w", err)
+			}
+		} else if err := os.Remove(path + tokenBackupSuffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to remove plaintext token backup: %w", err)
 		}
 	}
 
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("unable to cache oauth token: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file in
+// the same directory, so path holds either the old or the new content
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	// CreateTemp creates the file with mode 0600
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
 	if err != nil {
-		fmt.Printf("Unable to cache oauth token: %v", err)
-		return
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op once renamed
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
 	}
-	defer f.Close()
 
-	json.NewEncoder(f).Encode(token)
+	// Persist the rename itself; not every platform can sync a directory
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
 }
@@ -27,7 +27,7 @@ func TestTokenFromFile(t *testing.T) {
 		json.NewEncoder(f).Encode(legacyToken)
 		f.Close()
 
-		tok, tokenFile, err := tokenFromFile(tokenPath)
+		tok, tokenFile, err := tokenFromFile(tokenPath, nil)
 		if err != nil {
 			t.Fatalf("tokenFromFile() error = %v", err)
 		}
@@ -62,7 +62,7 @@ func TestTokenFromFile(t *testing.T) {
 		json.NewEncoder(f).Encode(enhancedToken)
 		f.Close()
 
-		tok, tokenFile, err := tokenFromFile(tokenPath)
+		tok, tokenFile, err := tokenFromFile(tokenPath, nil)
 		if err != nil {
 			t.Fatalf("tokenFromFile() error = %v", err)
 		}
@@ -76,7 +76,7 @@ func TestTokenFromFile(t *testing.T) {
 	})
 
 	t.Run("File Not Found", func(t *testing.T) {
-		_, _, err := tokenFromFile("non-existent-file.json")
+		_, _, err := tokenFromFile("non-existent-file.json", nil)
 		if err == nil {
 			t.Error("Expected error for non-existent file, got nil")
 		}
@@ -103,10 +103,12 @@ func TestSaveToken(t *testing.T) {
 	tokenData.Refresh(token)
 
 	// Save with config (enhanced)
-	saveToken(tokenPath, tokenData)
+	if err := saveToken(tokenPath, tokenData, nil); err != nil {
+		t.Fatalf("saveToken() error = %v", err)
+	}
 
 	// Read back
-	_, tokenFile, err := tokenFromFile(tokenPath)
+	_, tokenFile, err := tokenFromFile(tokenPath, nil)
 	if err != nil {
 		t.Fatalf("Failed to read back saved token: %v", err)
 	}
@@ -118,3 +120,54 @@ func TestSaveToken(t *testing.T) {
 		t.Errorf("Saved ClientID mismatch. Got %s, Want %s", tokenFile.ClientID, config.ClientID)
 	}
 }
+
+func TestSaveToken_Backup(t *testing.T) {
+	tempDir := t.TempDir()
+	tokenPath := filepath.Join(tempDir, "token.json")
+
+	if err := saveToken(tokenPath, &TokenFile{AccessToken: "first"}, nil); err != nil {
+		t.Fatalf("saveToken() error = %v", err)
+	}
+	if _, err := os.Stat(tokenPath + tokenBackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup for the first token, got err = %v", err)
+	}
+
+	if err := saveToken(tokenPath, &TokenFile{AccessToken: "second"}, nil); err != nil {
+		t.Fatalf("saveToken() error = %v", err)
+	}
+
+	_, current, err := tokenFromFile(tokenPath, nil)
+	if err != nil || current.AccessToken != "second" {
+		t.Errorf("token = %+v (err %v), want the second token", current, err)
+	}
+	_, backup, err := tokenFromFile(tokenPath+tokenBackupSuffix, nil)
+	if err != nil || backup.AccessToken != "first" {
+		t.Errorf("backup = %+v (err %v), want the first token", backup, err)
+	}
+
+	info, err := os.Stat(tokenPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("token mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// Temporary files are renamed or removed
+	entries, _ := os.ReadDir(tempDir)
+	for _, e := range entries {
+		if e.Name() != "token.json" && e.Name() != "token.json"+tokenBackupSuffix {
+			t.Errorf("unexpected file %s left in token directory", e.Name())
+		}
+	}
+}
+
+func TestSaveToken_Error(t *testing.T) {
+	// The parent of the token is a file, so the directory cannot be created
+	parent := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(parent, []byte("x"), 0600)
+
+	if err := saveToken(filepath.Join(parent, "token.json"), &TokenFile{}, nil); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+// Environment variables holding the token encryption secret when --token-key-file is not set
+const (
+	// TokenKeyEnv holds a base64-encoded 32-byte key, e.g. from `openssl rand -base64 32`
+	TokenKeyEnv = "GDU_TOKEN_KEY"
+	// TokenPassphraseEnv holds a passphrase the key is derived from
+	TokenPassphraseEnv = "GDU_TOKEN_PASSPHRASE"
+)
+
+const (
+	tokenEnvelopeFormat  = "gdu-encrypted-token"
+	tokenEnvelopeVersion = 1
+	tokenCipher          = "aes-256-gcm"
+	kdfNone              = "none"
+	kdfPBKDF2            = "pbkdf2-sha256"
+	tokenKeySize         = 32
+)
+
+// passphraseIterations is the PBKDF2 work factor of newly encrypted tokens.
+// The envelope records it, so raising it does not break existing files.
+var passphraseIterations = 600000
+
+// tokenEnvelope is the versioned on-disk format of an encrypted TokenFile.
+// Byte fields are base64 in JSON.
+type tokenEnvelope struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Cipher     string `json:"cipher"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// TokenKey is the secret token files are encrypted with: either a raw
+// 32-byte key or a passphrase the key is derived from.
+type TokenKey struct {
+	key        []byte
+	passphrase string
+	source     string
+}
+
+// String describes where the key came from, never the key itself
+func (k *TokenKey) String() string {
+	return k.source
+}
+
+// LoadTokenKey returns the token encryption key from keyFile, or else from the
+// GDU_TOKEN_KEY or GDU_TOKEN_PASSPHRASE environment variables. It returns nil
+// when none is set, in which case tokens are stored as plaintext.
+func LoadTokenKey(keyFile string) (*TokenKey, error) {
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read token key file: %v", err)
+		}
+		key, err := decodeTokenKey(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token key file '%s': %v", keyFile, err)
+		}
+		return &TokenKey{key: key, source: keyFile}, nil
+	}
+
+	if v := os.Getenv(TokenKeyEnv); v != "" {
+		key, err := decodeTokenKey(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", TokenKeyEnv, err)
+		}
+		return &TokenKey{key: key, source: "$" + TokenKeyEnv}, nil
+	}
+
+	if v := os.Getenv(TokenPassphraseEnv); v != "" {
+		return &TokenKey{passphrase: v, source: "$" + TokenPassphraseEnv}, nil
+	}
+
+	return nil, nil
+}
+
+// decodeTokenKey decodes a base64 key, ignoring surrounding whitespace
+func decodeTokenKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64: %v", err)
+	}
+	if len(key) != tokenKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", tokenKeySize, len(key))
+	}
+	return key, nil
+}
+
+// aead returns the cipher for an envelope, deriving the key from the
+// passphrase with the envelope's salt and iterations
+func (k *TokenKey) aead(env *tokenEnvelope) (cipher.AEAD, error) {
+	key := k.key
+	switch env.KDF {
+	case kdfNone:
+		if key == nil {
+			return nil, fmt.Errorf("token was encrypted with a key, not a passphrase")
+		}
+	case kdfPBKDF2:
+		if k.passphrase == "" {
+			return nil, fmt.Errorf("token was encrypted with a passphrase, not a key")
+		}
+		var err error
+		key, err = pbkdf2.Key(sha256.New, k.passphrase, env.Salt, env.Iterations, tokenKeySize)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key derivation '%s'", env.KDF)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// envelopeAAD binds the ciphertext to the envelope format and version
+func envelopeAAD(env *tokenEnvelope) []byte {
+	return fmt.Appendf(nil, "%s/%d", env.Format, env.Version)
+}
+
+// encryptToken seals the JSON of a token into an envelope
+func encryptToken(token *TokenFile, key *TokenKey) (*tokenEnvelope, error) {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &tokenEnvelope{
+		Format:  tokenEnvelopeFormat,
+		Version: tokenEnvelopeVersion,
+		Cipher:  tokenCipher,
+		KDF:     kdfNone,
+	}
+	if key.key == nil {
+		env.KDF = kdfPBKDF2
+		env.Iterations = passphraseIterations
+		env.Salt = make([]byte, 16)
+		if _, err := rand.Read(env.Salt); err != nil {
+			return nil, err
+		}
+	}
+
+	aead, err := key.aead(env)
+	if err != nil {
+		return nil, err
+	}
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, plaintext, envelopeAAD(env))
+	return env, nil
+}
+
+// decryptToken opens an envelope; a wrong key and a tampered file are the same error
+func decryptToken(env *tokenEnvelope, key *TokenKey) (*TokenFile, error) {
+	if env.Version != tokenEnvelopeVersion {
+		return nil, fmt.Errorf("unsupported encrypted token version %d, upgrade the uploader", env.Version)
+	}
+	if env.Cipher != tokenCipher {
+		return nil, fmt.Errorf("unsupported token cipher '%s'", env.Cipher)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("token is encrypted, provide the key with --token-key-file, %s or %s", TokenKeyEnv, TokenPassphraseEnv)
+	}
+
+	aead, err := key.aead(env)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted token")
+	}
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, envelopeAAD(env))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt token with %s: wrong key or corrupted file", key)
+	}
+
+	tokenFile := &TokenFile{}
+	if err := json.Unmarshal(plaintext, tokenFile); err != nil {
+		return nil, err
+	}
+	return tokenFile, nil
+}
+
+// decodeTokenFile decodes a token file, either an encrypted envelope or legacy plaintext JSON
+func decodeTokenFile(data []byte, key *TokenKey) (*TokenFile, error) {
+	env := &tokenEnvelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, err
+	}
+	if env.Format == tokenEnvelopeFormat {
+		return decryptToken(env, key)
+	}
+
+	tokenFile := &TokenFile{}
+	if err := json.Unmarshal(data, tokenFile); err != nil {
+		return nil, err
+	}
+	return tokenFile, nil
+}
+
+// tokenBackup returns the backup to keep of a previous token file. With a key,
+// a plaintext token is backed up encrypted, so encrypting a token leaves no
+// plaintext copy behind; ok is false when it cannot be read to do so.
+func tokenBackup(previous []byte, key *TokenKey) (backup []byte, ok bool) {
+	env := &tokenEnvelope{}
+	if key == nil || (json.Unmarshal(previous, env) == nil && env.Format == tokenEnvelopeFormat) {
+		return previous, true
+	}
+
+	tokenFile := &TokenFile{}
+	if err := json.Unmarshal(previous, tokenFile); err != nil {
+		return nil, false
+	}
+	data, err := encodeTokenFile(tokenFile, key)
+	if err != nil {
+		return nil, false
+	}
+	return append(data, '\n'), true
+}
+
+// encodeTokenFile returns the bytes to write for a token, encrypted when a key is set
+func encodeTokenFile(token *TokenFile, key *TokenKey) ([]byte, error) {
+	if key == nil {
+		return json.Marshal(token)
+	}
+	env, err := encryptToken(token, key)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// EncryptToken rewrites the configured token (file or helper) encrypted with
+// key. A plaintext token is migrated; an encrypted one is re-encrypted, which
+// rotates the nonce and salt.
+func EncryptToken(cfg config.Config, key *TokenKey) error {
+	if key == nil {
+		return fmt.Errorf("no token key, set --token-key-file, %s or %s", TokenKeyEnv, TokenPassphraseEnv)
+	}
+
+	store := newTokenStore(cfg, key)
+	unlock, err := store.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, tokenFile, err := store.Load()
+	if err != nil {
+		return err
+	}
+	return store.Save(tokenFile)
+}
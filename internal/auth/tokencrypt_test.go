@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+func testTokenKey(b byte) *TokenKey {
+	return &TokenKey{key: bytes.Repeat([]byte{b}, tokenKeySize), source: "test"}
+}
+
+func TestTokenEncryption(t *testing.T) {
+	// Keep passphrase derivation fast in tests
+	defer func(n int) { passphraseIterations = n }(passphraseIterations)
+	passphraseIterations = 1000
+
+	token := &TokenFile{
+		AccessToken:  "access-token",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh-token",
+		Expiry:       time.Now().Add(time.Hour).Round(0),
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+	}
+
+	tests := []struct {
+		name    string
+		encrypt *TokenKey
+		decrypt *TokenKey
+		wantErr string
+	}{
+		{name: "Key", encrypt: testTokenKey(1), decrypt: testTokenKey(1)},
+		{name: "Passphrase", encrypt: &TokenKey{passphrase: "secret"}, decrypt: &TokenKey{passphrase: "secret"}},
+		{name: "Wrong Key", encrypt: testTokenKey(1), decrypt: testTokenKey(2), wantErr: "wrong key"},
+		{name: "Wrong Passphrase", encrypt: &TokenKey{passphrase: "secret"}, decrypt: &TokenKey{passphrase: "other"}, wantErr: "wrong key"},
+		{name: "Passphrase For Key", encrypt: testTokenKey(1), decrypt: &TokenKey{passphrase: "secret"}, wantErr: "not a passphrase"},
+		{name: "No Key", encrypt: testTokenKey(1), decrypt: nil, wantErr: "token is encrypted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeTokenFile(token, tt.encrypt)
+			if err != nil {
+				t.Fatalf("encodeTokenFile() error = %v", err)
+			}
+			if bytes.Contains(data, []byte("refresh-token")) || bytes.Contains(data, []byte("client-secret")) {
+				t.Fatalf("encrypted token contains plaintext secrets: %s", data)
+			}
+
+			got, err := decodeTokenFile(data, tt.decrypt)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("decodeTokenFile() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeTokenFile() error = %v", err)
+			}
+			if got.RefreshToken != token.RefreshToken || got.ClientSecret != token.ClientSecret || !got.Expiry.Equal(token.Expiry) {
+				t.Errorf("decodeTokenFile() = %+v, want %+v", got, token)
+			}
+		})
+	}
+
+	t.Run("Tampered Ciphertext", func(t *testing.T) {
+		env, err := encryptToken(token, testTokenKey(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.Ciphertext[0] ^= 0xff
+		data, _ := json.Marshal(env)
+		if _, err := decodeTokenFile(data, testTokenKey(1)); err == nil {
+			t.Error("Expected error for tampered token, got nil")
+		}
+	})
+
+	t.Run("Unsupported Version", func(t *testing.T) {
+		env, err := encryptToken(token, testTokenKey(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.Version = 2
+		data, _ := json.Marshal(env)
+		if _, err := decodeTokenFile(data, testTokenKey(1)); err == nil || !strings.Contains(err.Error(), "version") {
+			t.Errorf("decodeTokenFile() error = %v, want unsupported version", err)
+		}
+	})
+
+	t.Run("Legacy Plaintext With Key", func(t *testing.T) {
+		data, _ := json.Marshal(token)
+		got, err := decodeTokenFile(data, testTokenKey(1))
+		if err != nil {
+			t.Fatalf("decodeTokenFile() error = %v", err)
+		}
+		if got.RefreshToken != token.RefreshToken {
+			t.Errorf("got RefreshToken = %v, want %v", got.RefreshToken, token.RefreshToken)
+		}
+	})
+}
+
+func TestLoadTokenKey(t *testing.T) {
+	tempDir := t.TempDir()
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, tokenKeySize))
+
+	keyPath := filepath.Join(tempDir, "token.key")
+	os.WriteFile(keyPath, []byte(encoded+"\n"), 0600)
+	shortPath := filepath.Join(tempDir, "short.key")
+	os.WriteFile(shortPath, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0600)
+
+	tests := []struct {
+		name       string
+		keyFile    string
+		env        map[string]string
+		wantSource string
+		wantErr    bool
+	}{
+		{name: "None", wantSource: ""},
+		{name: "Key File", keyFile: keyPath, env: map[string]string{TokenKeyEnv: "ignored"}, wantSource: keyPath},
+		{name: "Key Env", env: map[string]string{TokenKeyEnv: encoded, TokenPassphraseEnv: "ignored"}, wantSource: "$" + TokenKeyEnv},
+		{name: "Passphrase Env", env: map[string]string{TokenPassphraseEnv: "secret"}, wantSource: "$" + TokenPassphraseEnv},
+		{name: "Short Key", keyFile: shortPath, wantErr: true},
+		{name: "Missing Key File", keyFile: filepath.Join(tempDir, "missing.key"), wantErr: true},
+		{name: "Invalid Key Env", env: map[string]string{TokenKeyEnv: "not base64!"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(TokenKeyEnv, "")
+			t.Setenv(TokenPassphraseEnv, "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			key, err := LoadTokenKey(tt.keyFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadTokenKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			source := ""
+			if key != nil {
+				source = key.String()
+			}
+			if source != tt.wantSource {
+				t.Errorf("LoadTokenKey() source = %q, want %q", source, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestEncryptToken(t *testing.T) {
+	t.Setenv(config.TokenJSONEnv, "")
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	legacy := TokenFile{AccessToken: "access-token", RefreshToken: "refresh-token", ClientID: "client-id"}
+	data, _ := json.Marshal(legacy)
+	os.WriteFile(tokenPath, data, 0600)
+
+	cfg := config.Config{TokenPath: tokenPath}
+	key := testTokenKey(3)
+	if err := EncryptToken(cfg, key); err != nil {
+		t.Fatalf("EncryptToken() error = %v", err)
+	}
+
+	if _, _, err := tokenFromFile(tokenPath, nil); err == nil {
+		t.Error("Expected error reading encrypted token without key, got nil")
+	}
+	tok, tokenFile, err := tokenFromFile(tokenPath, key)
+	if err != nil {
+		t.Fatalf("tokenFromFile() error = %v", err)
+	}
+	if tok.RefreshToken != legacy.RefreshToken || tokenFile.ClientID != legacy.ClientID {
+		t.Errorf("tokenFromFile() = %+v, want %+v", tokenFile, legacy)
+	}
+
+	// The backup of the plaintext token is encrypted too, and no other copy is left
+	entries, _ := os.ReadDir(filepath.Dir(tokenPath))
+	for _, entry := range entries {
+		content, _ := os.ReadFile(filepath.Join(filepath.Dir(tokenPath), entry.Name()))
+		if strings.Contains(string(content), "refresh-token") {
+			t.Errorf("%s holds the plaintext token after EncryptToken()", entry.Name())
+		}
+	}
+	_, backup, err := tokenFromFile(tokenPath+tokenBackupSuffix, key)
+	if err != nil {
+		t.Fatalf("tokenFromFile() of backup error = %v", err)
+	}
+	if backup.RefreshToken != legacy.RefreshToken {
+		t.Errorf("backup = %+v, want %+v", backup, legacy)
+	}
+
+	if err := EncryptToken(cfg, nil); err == nil {
+		t.Error("Expected error encrypting without key, got nil")
+	}
+}
+
+func TestSaveToken_PlaintextBackupRemoved(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	// A backup of an earlier plaintext token, and a token that is not valid JSON
+	os.WriteFile(tokenPath+tokenBackupSuffix, []byte(`{"refresh_token": "refresh-token"}`), 0600)
+	os.WriteFile(tokenPath, []byte(`{"refresh_token": "refresh-token"`), 0600)
+
+	if err := saveToken(tokenPath, &TokenFile{AccessToken: "access-token"}, testTokenKey(4)); err != nil {
+		t.Fatalf("saveToken() error = %v", err)
+	}
+
+	// Neither can be encrypted, so no backup is kept rather than a plaintext one
+	if _, err := os.Stat(tokenPath + tokenBackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the plaintext backup to be removed, got err = %v", err)
+	}
+}
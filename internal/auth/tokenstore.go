@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/oauth2"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+// errReadOnlyStore is returned by Save when a store cannot keep refreshed
+// tokens; the token still works for the current run
+var errReadOnlyStore = errors.New("the refreshed token is only kept in memory")
+
+// tokenStore is where the OAuth token is loaded from and where new and
+// refreshed tokens are saved
+type tokenStore interface {
+	// Load returns the stored token, or an error if there is none or it cannot be read
+	Load() (*oauth2.Token, *TokenFile, error)
+	// Save stores a token, encrypted if the store has a key
+	Save(token *TokenFile) error
+	// Lock serializes Load-compare-Save cycles between processes sharing the store
+	Lock() (unlock func(), err error)
+	// String describes the store in messages
+	String() string
+}
+
+// newTokenStore returns the store selected by the configuration: the
+// --token-helper, else GDU_TOKEN_JSON (except when generating a token), else
+// the --token-path file
+func newTokenStore(cfg config.Config, key *TokenKey) tokenStore {
+	if cfg.TokenHelper != "" {
+		return &helperTokenStore{command: cfg.TokenHelper, key: key}
+	}
+	if value := os.Getenv(config.TokenJSONEnv); value != "" && !cfg.TokenGen {
+		return &envTokenStore{value: value, key: key}
+	}
+	return &fileTokenStore{path: cfg.TokenPath, key: key}
+}
+
+// fileTokenStore keeps the token in a local file
+type fileTokenStore struct {
+	path string
+	key  *TokenKey
+}
+
+func (s *fileTokenStore) Load() (*oauth2.Token, *TokenFile, error) {
+	return tokenFromFile(s.path, s.key)
+}
+
+func (s *fileTokenStore) Save(token *TokenFile) error {
+	err := saveToken(s.path, token, s.key)
+	// e.g. a Kubernetes secret volume
+	if errors.Is(err, syscall.EROFS) {
+		return fmt.Errorf("%s is on a read-only file system, %w", s.path, errReadOnlyStore)
+	}
+	return err
+}
+
+// Lock locks a .lock file next to the token, so concurrent runs do not
+// refresh and rewrite the token at the same time
+func (s *fileTokenStore) Lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil && !errors.Is(err, syscall.EROFS) {
+		return nil, fmt.Errorf("unable to create directory for token: %w", err)
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	// Nobody can write a token on a read-only file system, so there is nothing to serialize
+	if errors.Is(err, syscall.EROFS) {
+		return func() {}, nil
+	}
+	return unlock, err
+}
+
+func (s *fileTokenStore) String() string {
+	return s.path
+}
+
+// envTokenStore reads the token from GDU_TOKEN_JSON, as JSON or base64 JSON.
+// It is read-only: refreshed tokens are only kept in memory, which is enough
+// as long as Google does not rotate the refresh token.
+type envTokenStore struct {
+	value string
+	key   *TokenKey
+}
+
+func (s *envTokenStore) Load() (*oauth2.Token, *TokenFile, error) {
+	data := []byte(strings.TrimSpace(s.value))
+	if !bytes.HasPrefix(data, []byte("{")) {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s is neither JSON nor base64: %v", config.TokenJSONEnv, err)
+		}
+		data = decoded
+	}
+
+	tok, tokenFile, err := tokenFromJSON(data, s.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %w", config.TokenJSONEnv, err)
+	}
+	return tok, tokenFile, nil
+}
+
+func (s *envTokenStore) Save(token *TokenFile) error {
+	return fmt.Errorf("%s is read-only, %w", config.TokenJSONEnv, errReadOnlyStore)
+}
+
+func (s *envTokenStore) Lock() (func(), error) {
+	return func() {}, nil
+}
+
+func (s *envTokenStore) String() string {
+	return "$" + config.TokenJSONEnv
+}
+
+// helperTokenStore delegates to an external command in the style of git
+// credential helpers: "<command> get" prints the token JSON on stdout and
+// "<command> store" receives it on stdin. The command runs through the shell,
+// so it may carry its own arguments.
+type helperTokenStore struct {
+	command string
+	key     *TokenKey
+}
+
+func (s *helperTokenStore) Load() (*oauth2.Token, *TokenFile, error) {
+	out, err := s.run("get", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil, fmt.Errorf("%s returned no token", s)
+	}
+
+	tok, tokenFile, err := tokenFromJSON(out, s.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid token from %s: %w", s, err)
+	}
+	return tok, tokenFile, nil
+}
+
+func (s *helperTokenStore) Save(token *TokenFile) error {
+	fmt.Printf("Saving credential through %s\n", s)
+	data, err := encodeTokenFile(token, s.key)
+	if err != nil {
+		return fmt.Errorf("unable to encrypt oauth token: %v", err)
+	}
+	_, err = s.run("store", append(data, '\n'))
+	return err
+}
+
+// Lock does nothing: concurrent access is up to the helper's backend
+func (s *helperTokenStore) Lock() (func(), error) {
+	return func() {}, nil
+}
+
+func (s *helperTokenStore) String() string {
+	return fmt.Sprintf("token helper '%s'", s.command)
+}
+
+// run calls the helper with an operation; its stderr is passed through so helpers can report problems
+func (s *helperTokenStore) run(operation string, stdin []byte) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", s.command+" "+operation)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to %s the token: %v", s, operation, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/config"
+)
+
+func TestNewTokenStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		env     string
+		wantStr string
+	}{
+		{name: "File", cfg: config.Config{TokenPath: "token.json"}, wantStr: "token.json"},
+		{name: "Env", cfg: config.Config{TokenPath: "token.json"}, env: "{}", wantStr: "$" + config.TokenJSONEnv},
+		{name: "Env Ignored For Token Gen", cfg: config.Config{TokenPath: "token.json", TokenGen: true}, env: "{}", wantStr: "token.json"},
+		{name: "Helper", cfg: config.Config{TokenPath: "token.json", TokenHelper: "vault-helper"}, env: "{}", wantStr: "token helper 'vault-helper'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(config.TokenJSONEnv, tt.env)
+			if got := newTokenStore(tt.cfg, nil).String(); got != tt.wantStr {
+				t.Errorf("newTokenStore() = %q, want %q", got, tt.wantStr)
+			}
+		})
+	}
+}
+
+func TestEnvTokenStore(t *testing.T) {
+	raw, _ := json.Marshal(TokenFile{AccessToken: "access-token", RefreshToken: "refresh-token", ClientID: "client-id"})
+	encrypted, _ := encodeTokenFile(&TokenFile{RefreshToken: "refresh-token"}, testTokenKey(1))
+
+	tests := []struct {
+		name    string
+		value   string
+		key     *TokenKey
+		wantErr bool
+	}{
+		{name: "Raw JSON", value: string(raw)},
+		{name: "Base64 JSON", value: base64.StdEncoding.EncodeToString(raw) + "\n"},
+		{name: "Encrypted", value: string(encrypted), key: testTokenKey(1)},
+		{name: "Encrypted Without Key", value: string(encrypted), wantErr: true},
+		{name: "Invalid", value: "not a token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &envTokenStore{value: tt.value, key: tt.key}
+			tok, _, err := store.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tok.RefreshToken != "refresh-token" {
+				t.Errorf("got RefreshToken = %v, want refresh-token", tok.RefreshToken)
+			}
+		})
+	}
+
+	t.Run("Read Only", func(t *testing.T) {
+		store := &envTokenStore{value: string(raw)}
+		if err := store.Save(&TokenFile{}); err == nil {
+			t.Error("Expected error saving to environment, got nil")
+		}
+	})
+}
+
+// writeHelper creates a token helper script keeping the token in a file next to it
+func writeHelper(t *testing.T, body string) (command string, tokenPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	tokenPath = filepath.Join(dir, "stored.json")
+	script := filepath.Join(dir, "helper.sh")
+	content := fmt.Sprintf("#!/bin/sh\nTOKEN=%s\n%s\n", tokenPath, body)
+	if err := os.WriteFile(script, []byte(content), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return script, tokenPath
+}
+
+func TestHelperTokenStore(t *testing.T) {
+	command, tokenPath := writeHelper(t, `case "$1" in
+get) [ -f "$TOKEN" ] && cat "$TOKEN" ;;
+store) cat > "$TOKEN" ;;
+esac
+exit 0`)
+
+	t.Run("Get Without Token", func(t *testing.T) {
+		store := &helperTokenStore{command: command}
+		if _, _, err := store.Load(); err == nil {
+			t.Error("Expected error for empty helper output, got nil")
+		}
+	})
+
+	t.Run("Store And Get", func(t *testing.T) {
+		store := &helperTokenStore{command: command, key: testTokenKey(1)}
+		if err := store.Save(&TokenFile{AccessToken: "access-token", RefreshToken: "refresh-token"}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		stored, _ := os.ReadFile(tokenPath)
+		var env tokenEnvelope
+		if err := json.Unmarshal(stored, &env); err != nil || env.Format != tokenEnvelopeFormat {
+			t.Errorf("helper received %s, want an encrypted token", stored)
+		}
+
+		tok, _, err := store.Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if tok.RefreshToken != "refresh-token" {
+			t.Errorf("got RefreshToken = %v, want refresh-token", tok.RefreshToken)
+		}
+	})
+
+	t.Run("Helper Failure", func(t *testing.T) {
+		failing, _ := writeHelper(t, "exit 1")
+		store := &helperTokenStore{command: failing}
+		if _, _, err := store.Load(); err == nil {
+			t.Error("Expected error from failing helper on get, got nil")
+		}
+		if err := store.Save(&TokenFile{}); err == nil {
+			t.Error("Expected error from failing helper on store, got nil")
+		}
+	})
+}
+
+func TestSavingTokenSource_Helper(t *testing.T) {
+	command, tokenPath := writeHelper(t, `case "$1" in
+get) [ -f "$TOKEN" ] && cat "$TOKEN" ;;
+store) cat > "$TOKEN" ;;
+esac
+exit 0`)
+	store := &helperTokenStore{command: command}
+	if err := store.Save(&TokenFile{AccessToken: "old-token", ClientID: "client-id"}); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed := &oauth2.Token{AccessToken: "new-token", RefreshToken: "refresh-token", Expiry: time.Now().Add(time.Hour)}
+	ts := &savingTokenSource{
+		source: oauth2.StaticTokenSource(refreshed),
+		store:  store,
+		config: &oauth2.Config{ClientID: "client-id"},
+	}
+	if _, err := ts.Token(); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	_, tokenFile, err := tokenFromFile(tokenPath, nil)
+	if err != nil {
+		t.Fatalf("helper did not store the refreshed token: %v", err)
+	}
+	if tokenFile.AccessToken != "new-token" || tokenFile.ClientID != "client-id" {
+		t.Errorf("helper stored %+v, want the refreshed token with its client ID", tokenFile)
+	}
+}
+
+// failingStore loads a fixed token and fails to save
+type failingStore struct {
+	saveErr error
+}
+
+func (s *failingStore) Load() (*oauth2.Token, *TokenFile, error) {
+	return &oauth2.Token{AccessToken: "old-token"}, &TokenFile{AccessToken: "old-token"}, nil
+}
+func (s *failingStore) Save(token *TokenFile) error { return s.saveErr }
+func (s *failingStore) Lock() (func(), error)       { return func() {}, nil }
+func (s *failingStore) String() string              { return "failing store" }
+
+func TestSavingTokenSource_SaveError(t *testing.T) {
+	refreshed := &oauth2.Token{AccessToken: "new-token", Expiry: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name    string
+		saveErr error
+		wantErr bool
+	}{
+		{name: "Saved", saveErr: nil},
+		{name: "Read Only Store", saveErr: fmt.Errorf("read-only, %w", errReadOnlyStore)},
+		{name: "Write Error", saveErr: fmt.Errorf("disk full"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := &savingTokenSource{
+				source: oauth2.StaticTokenSource(refreshed),
+				store:  &failingStore{saveErr: tt.saveErr},
+				config: &oauth2.Config{},
+			}
+			tok, err := ts.Token()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Token() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, errTokenNotSaved) {
+					t.Errorf("Token() error = %v, want errTokenNotSaved", err)
+				}
+				return
+			}
+			if tok.AccessToken != "new-token" {
+				t.Errorf("got AccessToken = %v, want new-token", tok.AccessToken)
+			}
+		})
+	}
+}
+
+// lockCheckingStore records whether each Save happened under Lock
+type lockCheckingStore struct {
+	locked      bool
+	savedLocked []bool
+	savedTokens []*TokenFile
+}
+
+func (s *lockCheckingStore) Load() (*oauth2.Token, *TokenFile, error) {
+	return nil, nil, fmt.Errorf("no token")
+}
+func (s *lockCheckingStore) Save(token *TokenFile) error {
+	s.savedLocked = append(s.savedLocked, s.locked)
+	s.savedTokens = append(s.savedTokens, token)
+	return nil
+}
+func (s *lockCheckingStore) Lock() (func(), error) {
+	s.locked = true
+	return func() { s.locked = false }, nil
+}
+func (s *lockCheckingStore) String() string { return "lock checking store" }
+
+func TestAuthenticator_SaveAuthorized(t *testing.T) {
+	store := &lockCheckingStore{}
+	a := &Authenticator{store: store}
+	tok := &oauth2.Token{AccessToken: "new-token", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}
+
+	if err := a.saveAuthorized(&oauth2.Config{ClientID: "client-id"}, tok); err != nil {
+		t.Fatalf("saveAuthorized() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(store.savedLocked, []bool{true}) {
+		t.Fatalf("saves under lock = %v, want one save while locked", store.savedLocked)
+	}
+	if store.locked {
+		t.Error("store still locked after saveAuthorized()")
+	}
+	if saved := store.savedTokens[0]; saved.AccessToken != "new-token" || saved.ClientID != "client-id" {
+		t.Errorf("saved %+v, want the new token with its client ID", saved)
+	}
+}
+
+// lockFailingStore cannot be locked, like a token in a directory the user cannot write
+type lockFailingStore struct {
+	locks int
+	saves int
+}
+
+func (s *lockFailingStore) Load() (*oauth2.Token, *TokenFile, error) {
+	return &oauth2.Token{AccessToken: "old-token"}, &TokenFile{AccessToken: "old-token"}, nil
+}
+func (s *lockFailingStore) Save(token *TokenFile) error {
+	s.saves++
+	return nil
+}
+func (s *lockFailingStore) Lock() (func(), error) {
+	s.locks++
+	return nil, fmt.Errorf("permission denied")
+}
+func (s *lockFailingStore) String() string { return "lock failing store" }
+
+func TestSavingTokenSource_LockError(t *testing.T) {
+	valid := &oauth2.Token{AccessToken: "valid-token", Expiry: time.Now().Add(time.Hour)}
+	expired := &oauth2.Token{AccessToken: "expired-token", Expiry: time.Now().Add(-time.Hour)}
+	refreshed := &oauth2.Token{AccessToken: "new-token", Expiry: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name      string
+		current   *oauth2.Token
+		want      string
+		wantLocks int
+	}{
+		{name: "Valid Token Is Not Locked", current: valid, want: "valid-token", wantLocks: 0},
+		{name: "Refresh Without Lock", current: expired, want: "new-token", wantLocks: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &lockFailingStore{}
+			ts := &savingTokenSource{
+				source:  oauth2.StaticTokenSource(refreshed),
+				store:   store,
+				config:  &oauth2.Config{},
+				current: tt.current,
+			}
+			tok, err := ts.Token()
+			if err != nil {
+				t.Fatalf("Token() error = %v", err)
+			}
+			if tok.AccessToken != tt.want {
+				t.Errorf("got AccessToken = %v, want %v", tok.AccessToken, tt.want)
+			}
+			if store.locks != tt.wantLocks || store.saves != 0 {
+				t.Errorf("locks = %d, saves = %d, want %d locks and no save", store.locks, store.saves, tt.wantLocks)
+			}
+		})
+	}
+}
+
+func TestFileTokenStore_Lock(t *testing.T) {
+	store := &fileTokenStore{path: filepath.Join(t.TempDir(), "token.json")}
+
+	unlock, err := store.Lock()
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := store.Lock()
+		if err != nil {
+			t.Errorf("Lock() error = %v", err)
+			close(acquired)
+			return
+		}
+		close(acquired)
+		unlock()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second Lock() returned while the first was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second Lock() did not return after unlock")
+	}
+}
@@ -0,0 +1,46 @@
+package cleanup
+
+import (
+	"context"
+	"strings"
+)
+
+// Cleanup actions
+const (
+	// ActionTrash moves expired items to trash (or deletes them with Permanent)
+	ActionTrash = "trash"
+	// ActionArchive moves expired items below an archive folder, keeping their path
+	ActionArchive = "archive"
+)
+
+// actionName returns the cleanup action, for plans
+func (c *CleanupService) actionName() string {
+	if c.action == ActionArchive {
+		return ActionArchive
+	}
+	return ActionTrash
+}
+
+// archive moves an item below the archive folder at the same relative path,
+// e.g. SERVICE/2025-01-01 to <archive>/SERVICE/2025-01-01, creating the
+// parent folders as needed
+func (c *CleanupService) archive(ctx context.Context, item PlanItem, archiveID string) error {
+	parentID := archiveID
+	segments := strings.Split(item.Path, "/")
+	for i, name := range segments[:len(segments)-1] {
+		path := strings.Join(segments[:i+1], "/")
+		if id, ok := c.archived[path]; ok {
+			parentID = id
+			continue
+		}
+
+		id, err := c.driveService.FindOrCreateFolder(ctx, name, parentID)
+		if err != nil {
+			return err
+		}
+		c.archived[path] = id
+		parentID = id
+	}
+
+	return c.driveService.MoveFile(ctx, item.ID, parentID)
+}
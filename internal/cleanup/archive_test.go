@@ -0,0 +1,111 @@
+package cleanup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestRunArchive(t *testing.T) {
+	folder := func(id string, name string) *drive.File {
+		return &drive.File{Id: id, Name: name}
+	}
+
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			// The archive folder lives below the root, so it must not be cleaned up itself
+			"root":     {folder("svc", "SERVICE"), folder("archive", "ARCHIVE")},
+			"svc":      {folder("y2024", "2024"), folder("y2025", "2025")},
+			"y2024":    {folder("y2024m12", "12")},
+			"y2025":    {folder("y2025m01", "01")},
+			"y2024m12": {folder("d20241231", "31")},
+			"y2025m01": {folder("d20250101", "01"), folder("d20250102", "02")},
+			"archive":  {folder("a2019", "2019")},
+			"a2019":    {folder("a201901", "01")},
+			"a201901":  {folder("a20190101", "01"), folder("a20190102", "02")},
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern:     "yyyy/MM/dd",
+		Retention:       RetentionPolicy{Keep: 1},
+		Action:          ActionArchive,
+		ArchiveFolderID: "archive",
+	})
+
+	archived, err := c.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	wantArchived := []string{
+		"SERVICE/2025/01/01",
+		"SERVICE/2024/12/31",
+		"SERVICE/2024/12",
+		"SERVICE/2024",
+	}
+	if !reflect.DeepEqual(archived, wantArchived) {
+		t.Errorf("Run() = %v, want %v", archived, wantArchived)
+	}
+
+	// Date folders keep their path below the archive folder
+	for path, id := range map[string]string{
+		"archive/SERVICE/2025/01": "d20250101",
+		"archive/SERVICE/2024/12": "d20241231",
+	} {
+		children := fake.folders[path]
+		if len(children) != 1 || children[0].Id != id {
+			t.Errorf("archive folder %s = %v, want [%s]", path, children, id)
+		}
+	}
+
+	// The emptied hierarchy folders hold nothing, so they are trashed rather than archived
+	wantTrashed := []string{"y2024m12", "y2024"}
+	if !reflect.DeepEqual(fake.trashed, wantTrashed) {
+		t.Errorf("trashed = %v, want %v", fake.trashed, wantTrashed)
+	}
+}
+
+func TestRunArchive_MoveFailed(t *testing.T) {
+	folder := func(id string, name string) *drive.File {
+		return &drive.File{Id: id, Name: name}
+	}
+
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root":     {folder("svc", "SERVICE"), folder("archive", "ARCHIVE")},
+			"svc":      {folder("y2024", "2024"), folder("y2025", "2025")},
+			"y2024":    {folder("y2024m12", "12")},
+			"y2025":    {folder("y2025m01", "01")},
+			"y2024m12": {folder("d20241231", "31")},
+			"y2025m01": {folder("d20250101", "01"), folder("d20250102", "02")},
+		},
+	}
+
+	c := NewCleanupService(&removeFailingDriveService{fakeDriveService: fake, failID: "d20241231"}, Options{
+		DatePattern:     "yyyy/MM/dd",
+		Retention:       RetentionPolicy{Keep: 1},
+		Action:          ActionArchive,
+		ArchiveFolderID: "archive",
+	})
+
+	archived, err := c.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	wantArchived := []string{"SERVICE/2025/01/01"}
+	if !reflect.DeepEqual(archived, wantArchived) {
+		t.Errorf("Run() = %v, want %v", archived, wantArchived)
+	}
+
+	// The leaf that could not be archived keeps its month and year folders out of trash
+	if len(fake.trashed) != 0 {
+		t.Errorf("trashed = %v, want nothing", fake.trashed)
+	}
+	if children := fake.folders["y2024m12"]; len(children) != 1 || children[0].Id != "d20241231" {
+		t.Errorf("folder 2024/12 = %v, want [d20241231]", children)
+	}
+}
@@ -4,163 +4,643 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/eliasferreira/google-drive-uploader/internal/parser"
 	"google.golang.org/api/drive/v3"
 )
 
 // DriveService defines the interface for Drive operations needed by cleanup
 type DriveService interface {
 	ListFolders(ctx context.Context, parentID string) ([]*drive.File, error)
+	ListFiles(ctx context.Context, parentID string) ([]*drive.File, error)
+	FolderSize(ctx context.Context, folderID string) (int64, error)
+	FindOrCreateFolder(ctx context.Context, name string, parentID string) (string, error)
+	MoveFile(ctx context.Context, fileID string, newParentID string) error
+	GetFile(ctx context.Context, fileID string) (*drive.File, error)
 	TrashFile(ctx context.Context, fileID string) error
+	UntrashFile(ctx context.Context, fileID string) error
+	DeleteFile(ctx context.Context, fileID string) error
+}
+
+// Cleanup targets
+const (
+	// TargetFolders applies retention to date-named folders
+	TargetFolders = "folders"
+	// TargetFiles applies retention to date-stamped files, grouped by service
+	TargetFiles = "files"
+)
+
+// Date sources, deciding how items are dated
+const (
+	// DateFromName parses the date from item names with the date pattern
+	DateFromName = "name"
+	// DateFromCreated uses the Drive createdTime
+	DateFromCreated = "created"
+	// DateFromModified uses the Drive modifiedTime
+	DateFromModified = "modified"
+)
+
+// Options configures a CleanupService
+type Options struct {
+	// DatePattern is the user-friendly date pattern folder names must match (e.g. yyyy-MM-dd)
+	DatePattern string
+	// MatchRegex is a raw regular expression with named date groups, used instead of DatePattern when set
+	MatchRegex string
+	// Retention decides which date folders are kept
+	Retention RetentionPolicy
+	// Target is either TargetFolders (default) or TargetFiles
+	Target string
+	// DateSource is DateFromName (default), DateFromCreated or DateFromModified.
+	// With a Drive time, the date pattern is an optional filter on item names.
+	DateSource string
+	// Permanent deletes expired items instead of moving them to trash
+	Permanent bool
+	// Action is ActionTrash (default) or ActionArchive, which moves expired
+	// items below ArchiveFolderID instead, keeping their path
+	Action          string
+	ArchiveFolderID string
+	// MaxDelete aborts before removing anything if a plan has more items (0 means no limit)
+	MaxDelete int
+	// MinAge protects items dated or modified more recently than this
+	MinAge time.Duration
+	// Concurrency is the number of Drive requests made in parallel while planning (1 or less is serial)
+	Concurrency int
+	// MaxDepth limits how many folder levels below the root are traversed (0 means no limit)
+	MaxDepth int
+	// PathInclude limits the cleanup to folders matching these globs and their
+	// subfolders, PathExclude skips folders matching these globs. Globs use
+	// path.Match syntax on paths relative to the root, e.g. "SERVICE-*/db".
+	PathInclude []string
+	PathExclude []string
+	// Parser extracts the service and date of file names (files target), the
+	// built-in [service]_backup_[date] convention when nil
+	Parser *parser.Parser
 }
 
 // CleanupService handles cleanup operations
 type CleanupService struct {
 	driveService DriveService
 	datePattern  string
-	keepCount    int
-	deletedPaths []string
+	matchRegex   string
+	compiled     *DatePattern
+	policy       RetentionPolicy
+	target       string
+	dateSource   string
+	permanent    bool
+	action       string
+	archiveID    string
+	// archived caches the folders created below the archive folder, by path
+	archived    map[string]string
+	maxDelete   int
+	minAge      time.Duration
+	concurrency int
+	scope       scope
+	parser      *parser.Parser
+	run         *RunLog
+
+	// failures lists the branches that could not be examined by the current plan
+	mu       sync.Mutex
+	failures []string
 }
 
 // NewCleanupService creates a new cleanup service
-func NewCleanupService(driveService DriveService, datePattern string, keepCount int) *CleanupService {
+func NewCleanupService(driveService DriveService, opts Options) *CleanupService {
+	if opts.Concurrency > 1 {
+		driveService = newLimitedDriveService(driveService, opts.Concurrency)
+	}
+
 	return &CleanupService{
 		driveService: driveService,
-		datePattern:  datePattern,
-		keepCount:    keepCount,
-		deletedPaths: make([]string, 0),
+		datePattern:  opts.DatePattern,
+		matchRegex:   opts.MatchRegex,
+		policy:       opts.Retention,
+		target:       opts.Target,
+		dateSource:   opts.DateSource,
+		permanent:    opts.Permanent,
+		action:       opts.Action,
+		archiveID:    opts.ArchiveFolderID,
+		archived:     make(map[string]string),
+		maxDelete:    opts.MaxDelete,
+		minAge:       opts.MinAge,
+		concurrency:  opts.Concurrency,
+		scope: scope{
+			include:  opts.PathInclude,
+			exclude:  opts.PathExclude,
+			maxDepth: opts.MaxDepth,
+		},
+		parser: opts.Parser,
 	}
 }
 
-// Run executes the cleanup process starting from rootFolderID
+// Run executes the cleanup process starting from rootFolderID.
+// If the run fails or ctx is cancelled, the paths removed so far are returned along with the error.
+// Branches that could not be examined do not stop the run, but are reported as an error once it is done.
 func (c *CleanupService) Run(ctx context.Context, rootFolderID string) ([]string, error) {
-	log.Printf("Starting cleanup with pattern '%s', keeping %d most recent folders", c.datePattern, c.keepCount)
+	plan, err := c.Plan(ctx, rootFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := c.Apply(ctx, plan, false)
+	if err == nil && len(plan.Errors) > 0 {
+		err = fmt.Errorf("%d folders could not be examined", len(plan.Errors))
+	}
+	return removed, err
+}
 
-	err := c.traverseFolders(ctx, rootFolderID, "")
+// Plan traverses rootFolderID and returns the items the retention policy
+// selects for removal, without changing anything in Drive
+func (c *CleanupService) Plan(ctx context.Context, rootFolderID string) (*Plan, error) {
+	var source string
+	if c.usesDriveTime() {
+		if _, err := c.nameFilter(); err != nil {
+			return nil, fmt.Errorf("invalid name filter: %w", err)
+		}
+		source = c.matchRegex
+		if source == "" {
+			source = c.datePattern
+		}
+		log.Printf("Planning cleanup by Drive %s time, name filter '%s', retention: %s", c.dateSource, source, c.policy)
+	} else {
+		pattern, err := c.pattern()
+		if err != nil {
+			return nil, fmt.Errorf("invalid date pattern: %w", err)
+		}
+		source = pattern.String()
+		log.Printf("Planning cleanup with pattern '%s', retention: %s", pattern, c.policy)
+	}
+
+	if err := c.scope.validate(); err != nil {
+		return nil, err
+	}
+
+	if c.action == ActionArchive && c.archiveID == "" {
+		return nil, fmt.Errorf("archiving requires an archive folder")
+	}
+
+	c.failures = nil
+	items, err := c.traverseFolders(ctx, rootFolderID, "", 0)
 	if err != nil {
 		return nil, err
 	}
+	if len(c.failures) > 0 {
+		log.Printf("Warning: %d folders could not be examined and were skipped", len(c.failures))
+	}
+
+	return &Plan{
+		Version:      planVersion,
+		CreatedAt:    now().UTC(),
+		RootFolderID: rootFolderID,
+		Target:       c.targetName(),
+		DateSource:   c.dateSourceName(),
+		Pattern:      source,
+		Retention:    c.policy.String(),
+		Permanent:    c.permanent,
+		Action:       c.actionName(),
+		ArchiveID:    c.archiveID,
+		Items:        items,
+		Errors:       c.failures,
+	}, nil
+}
+
+// Apply removes the items of a plan, trashing, archiving or permanently
+// deleting them as the plan says. Nothing is removed if the plan exceeds MaxDelete, and items
+// younger than MinAge are skipped. With verify, each item is re-checked first
+// and skipped if it changed since the plan was made. Empty parents are always
+// re-checked, so a child that failed is never removed along with them. If ctx
+// is cancelled, the paths removed so far are returned along with the error.
+func (c *CleanupService) Apply(ctx context.Context, plan *Plan, verify bool) ([]string, error) {
+	if err := CheckMaxDelete(plan, c.maxDelete); err != nil {
+		return nil, err
+	}
+
+	c.run = newRunLog(plan)
+	removed := make([]string, 0)
+	for _, item := range plan.Items {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		if reason := c.tooRecent(item.Date, item.ModifiedTime); reason != "" {
+			log.Printf("Skipping %s: %s", item.Path, reason)
+			continue
+		}
+
+		if verify {
+			if err := c.verifyItem(ctx, item); err != nil {
+				log.Printf("Skipping %s: %v", item.Path, err)
+				continue
+			}
+		} else if item.Rule == ReasonEmptyParent {
+			// Its children come first in the plan, but may have failed to be
+			// removed; removing the parent would take them along
+			empty, err := c.isEmpty(ctx, item.ID, nil)
+			if err != nil {
+				log.Printf("Skipping %s: unable to check item: %v", item.Path, err)
+				continue
+			}
+			if !empty {
+				log.Printf("Skipping %s: folder is not empty", item.Path)
+				continue
+			}
+		}
+
+		if err := c.remove(ctx, item, plan); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		removed = append(removed, item.Path)
+		c.record(item)
+	}
 
-	log.Printf("Cleanup completed. Total folders moved to trash: %d", len(c.deletedPaths))
-	return c.deletedPaths, nil
+	log.Printf("Cleanup completed. Total %s %s: %d", c.targetName(), plan.Outcome(), len(removed))
+	return removed, nil
 }
 
-// FolderWithDate holds a folder and its parsed date
+// targetName returns the kind of item being cleaned up, for log messages
+func (c *CleanupService) targetName() string {
+	if c.target == TargetFiles {
+		return "files"
+	}
+	return "folders"
+}
+
+// FolderWithDate holds a folder (or a date-stamped file) and its parsed date
 type FolderWithDate struct {
 	File *drive.File
 	Date time.Time
+	// Size is the total size in bytes, only computed when the retention policy needs it
+	Size int64
+	// Path is the path relative to the group's parent folder when it differs
+	// from File.Name, e.g. "2025/12/24" for a date hierarchy
+	Path string
 }
 
-// traverseFolders recursively traverses folders and applies retention policy
-func (c *CleanupService) traverseFolders(ctx context.Context, folderID string, currentPath string) error {
+// name returns the path of the item relative to its group's parent folder
+func (f FolderWithDate) name() string {
+	if f.Path != "" {
+		return f.Path
+	}
+	return f.File.Name
+}
+
+// displayPath returns path for log messages, naming the root folder when empty
+func displayPath(path string) string {
+	if path == "" {
+		return "root folder"
+	}
+	return path
+}
+
+// joinPath appends name to a slash-separated Drive path
+func joinPath(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+// traverseFolders recursively traverses folders and returns the items the retention policy selects.
+// depth is the number of folder levels between the root and folderID.
+func (c *CleanupService) traverseFolders(ctx context.Context, folderID string, currentPath string, depth int) ([]PlanItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if c.usesDriveTime() {
+		return c.traverseByDriveTime(ctx, folderID, currentPath, depth)
+	}
+
 	folders, err := c.driveService.ListFolders(ctx, folderID)
 	if err != nil {
-		return fmt.Errorf("failed to list folders in '%s': %v", currentPath, err)
+		return nil, fmt.Errorf("failed to list folders in '%s': %v", currentPath, err)
 	}
 
-	if len(folders) == 0 {
-		return nil
+	if len(folders) == 0 && c.target != TargetFiles {
+		return nil, nil
+	}
+
+	files, err := c.driveService.ListFiles(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in '%s': %v", currentPath, err)
+	}
+	if hasKeepMarker(files) {
+		log.Printf("Protected: %s (contains %s)", displayPath(currentPath), KeepMarker)
+		return nil, nil
+	}
+
+	// Folders on the way to a --path-include are only traversed
+	examine := c.scope.included(currentPath)
+
+	// In file mode every folder may hold date-stamped files, so all of them are traversed
+	if c.target == TargetFiles {
+		var items []PlanItem
+		if examine {
+			if items, err = c.cleanupFiles(ctx, files, currentPath); err != nil {
+				return nil, err
+			}
+		}
+		subItems, err := c.traverseSubfolders(ctx, folders, currentPath, depth)
+		return append(items, subItems...), err
+	}
+
+	pattern, err := c.pattern()
+	if err != nil {
+		return nil, err
 	}
 
 	// Separate folders into date-matching and non-date-matching
 	var dateFolders []FolderWithDate
 	var nonDateFolders []*drive.File
+	var branches []dateBranch
 
 	for _, folder := range folders {
-		matches, parsedDate := c.matchesDatePattern(folder.Name)
-		if matches {
+		// The first level of a date hierarchy (e.g. the year of yyyy/MM/dd) is only part of a date
+		if pattern.Levels() > 1 {
+			if fields, ok := pattern.MatchLevel(0, folder.Name); ok {
+				if !examine {
+					continue
+				}
+				err := c.collectHierarchy(ctx, pattern, folder, folder.Name, 1, fields, &dateFolders, &branches)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+		} else if matches, parsedDate := c.matchesDatePattern(folder.Name); matches {
+			if !examine {
+				continue
+			}
 			dateFolders = append(dateFolders, FolderWithDate{
 				File: folder,
 				Date: parsedDate,
 			})
-		} else {
-			nonDateFolders = append(nonDateFolders, folder)
+			continue
 		}
+		nonDateFolders = append(nonDateFolders, folder)
 	}
 
-	// If we have multiple date-matching folders, apply retention policy
-	if len(dateFolders) > c.keepCount {
-		err := c.applyRetentionPolicy(ctx, dateFolders, currentPath)
+	if err := c.computeSizes(ctx, dateFolders, currentPath); err != nil {
+		return nil, err
+	}
+
+	// If we have date-matching folders, apply retention policy
+	var items []PlanItem
+	if len(dateFolders) > 0 {
+		if items, err = c.applyRetentionPolicy(ctx, dateFolders, currentPath); err != nil {
+			return nil, err
+		}
+		emptyBranches, err := c.planEmptyBranches(ctx, branches, currentPath, items)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		items = append(items, emptyBranches...)
 	}
 
 	// Recursively traverse non-date folders
-	for _, folder := range nonDateFolders {
-		newPath := currentPath
-		if newPath == "" {
-			newPath = folder.Name
-		} else {
-			newPath = currentPath + "/" + folder.Name
-		}
-		err := c.traverseFolders(ctx, folder.Id, newPath)
+	subItems, err := c.traverseSubfolders(ctx, nonDateFolders, currentPath, depth)
+	return append(items, subItems...), err
+}
+
+// computeSizes fills in the size of each folder. Folder sizes are expensive to
+// compute, so they are only fetched for size-based retention.
+func (c *CleanupService) computeSizes(ctx context.Context, folders []FolderWithDate, currentPath string) error {
+	if !c.policy.UsesSize() {
+		return nil
+	}
+
+	for i := range folders {
+		size, err := c.driveService.FolderSize(ctx, folders[i].File.Id)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to compute size of '%s': %v", joinPath(currentPath, folders[i].name()), err)
 		}
+		folders[i].Size = size
 	}
-
 	return nil
 }
 
-// applyRetentionPolicy sorts folders by date and keeps only the most recent ones
-func (c *CleanupService) applyRetentionPolicy(ctx context.Context, folders []FolderWithDate, parentPath string) error {
-	// Sort by date descending (most recent first)
-	sort.Slice(folders, func(i, j int) bool {
-		return folders[i].Date.After(folders[j].Date)
-	})
+// traverseSubfolders calls traverseFolders for each folder below currentPath,
+// in parallel when Concurrency allows it. A folder that cannot be examined is
+// recorded as a failure and skipped, so one bad branch does not abort the
+// whole cleanup. Items are returned in folder order whatever the concurrency.
+func (c *CleanupService) traverseSubfolders(ctx context.Context, folders []*drive.File, currentPath string, depth int) ([]PlanItem, error) {
+	results := make([][]PlanItem, len(folders))
+	errs := make([]error, len(folders))
+	paths := make([]string, len(folders))
 
-	// Keep the first keepCount folders, trash the rest
-	for i := c.keepCount; i < len(folders); i++ {
-		folder := folders[i].File
-		fullPath := parentPath
-		if fullPath == "" {
-			fullPath = folder.Name
-		} else {
-			fullPath = parentPath + "/" + folder.Name
+	var wg sync.WaitGroup
+	for i, folder := range folders {
+		paths[i] = joinPath(currentPath, folder.Name)
+		if !c.shouldTraverse(folder, paths[i], depth+1) {
+			continue
 		}
 
-		log.Printf("Moving to trash: %s (date: %s)", fullPath, folders[i].Date.Format("2006-01-02"))
+		if c.concurrency <= 1 {
+			results[i], errs[i] = c.traverseFolders(ctx, folder.Id, paths[i], depth+1)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = c.traverseFolders(ctx, folder.Id, paths[i], depth+1)
+		}()
+	}
+	wg.Wait()
+
+	var items []PlanItem
+	for i := range folders {
+		if errs[i] != nil {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			log.Printf("Warning: skipping '%s': %v", paths[i], errs[i])
+			c.addFailure(fmt.Sprintf("%s: %v", paths[i], errs[i]))
+			continue
+		}
+		items = append(items, results[i]...)
+	}
+
+	return items, nil
+}
+
+// shouldTraverse reports whether a folder at depth levels below the root is traversed
+func (c *CleanupService) shouldTraverse(folder *drive.File, path string, depth int) bool {
+	if isPinned(folder) {
+		log.Printf("Protected: %s (%s)", path, PinnedProperty)
+		return false
+	}
+	if c.archiveID != "" && folder.Id == c.archiveID {
+		log.Printf("Skipping archive folder: %s", path)
+		return false
+	}
+	if c.scope.excluded(path) {
+		log.Printf("Excluded: %s", path)
+		return false
+	}
+	if !c.scope.included(path) && !c.scope.leadsToInclude(path) {
+		return false
+	}
+	return c.scope.maxDepth <= 0 || depth <= c.scope.maxDepth
+}
+
+// addFailure records a branch that could not be examined
+func (c *CleanupService) addFailure(failure string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = append(c.failures, failure)
+}
+
+// applyRetentionPolicy sorts folders by date and returns the items to remove: those not kept by any retention rule
+// and not protected
+func (c *CleanupService) applyRetentionPolicy(ctx context.Context, folders []FolderWithDate, parentPath string) ([]PlanItem, error) {
+	kept, expired := c.policy.Evaluate(folders)
+
+	if c.policy.UsesSize() {
+		var total, keptTotal int64
+		for i := range folders {
+			total += folders[i].Size
+			if _, ok := kept[i]; ok {
+				keptTotal += folders[i].Size
+			}
+		}
+		log.Printf("Size of '%s': %s, keeping %s (budget: %s)", parentPath, FormatSize(total), FormatSize(keptTotal), FormatSize(c.policy.MaxSize))
+	}
+
+	var items []PlanItem
+	for i := range folders {
+		fullPath := joinPath(parentPath, folders[i].name())
+
+		if rules, ok := kept[i]; ok {
+			log.Printf("Keeping: %s (date: %s, rules: %s)", fullPath, c.formatDate(folders[i].Date), strings.Join(rules, ", "))
+			continue
+		}
 
-		err := c.driveService.TrashFile(ctx, folder.Id)
+		if c.scope.excluded(fullPath) {
+			log.Printf("Excluded: %s (date: %s)", fullPath, c.formatDate(folders[i].Date))
+			continue
+		}
+
+		reason, err := c.protection(ctx, folders[i])
 		if err != nil {
-			log.Printf("Warning: Failed to trash folder '%s': %v", fullPath, err)
+			return nil, fmt.Errorf("failed to check protection of '%s': %v", fullPath, err)
+		}
+		if reason != "" {
+			log.Printf("Protected: %s (date: %s, %s)", fullPath, c.formatDate(folders[i].Date), reason)
 			continue
 		}
 
-		c.deletedPaths = append(c.deletedPaths, fullPath)
+		log.Printf("Selected for removal: %s (date: %s, rule: %s)", fullPath, c.formatDate(folders[i].Date), expired[i])
+		items = append(items, PlanItem{
+			ID:           folders[i].File.Id,
+			Path:         fullPath,
+			Date:         folders[i].Date,
+			Rule:         expired[i],
+			ModifiedTime: folders[i].File.ModifiedTime,
+		})
+	}
+
+	return items, nil
+}
+
+// remove trashes, archives or, if permanent, deletes a planned item.
+// Empty date hierarchy folders hold nothing worth archiving, so they are trashed;
+// Apply checks first that they are still empty, e.g. that no child failed to move.
+func (c *CleanupService) remove(ctx context.Context, item PlanItem, plan *Plan) error {
+	detail := "rule: " + item.Rule
+	if !item.Date.IsZero() {
+		detail = fmt.Sprintf("date: %s, %s", c.formatDate(item.Date), detail)
+	}
+
+	if plan.Action == ActionArchive && item.Rule != ReasonEmptyParent {
+		log.Printf("Archiving: %s (%s)", item.Path, detail)
+		if err := c.archive(ctx, item, plan.ArchiveID); err != nil {
+			return fmt.Errorf("failed to archive '%s': %v", item.Path, err)
+		}
+		return nil
+	}
+
+	if plan.Permanent {
+		log.Printf("Permanently deleting: %s (%s)", item.Path, detail)
+		if err := c.driveService.DeleteFile(ctx, item.ID); err != nil {
+			return fmt.Errorf("failed to delete '%s': %v", item.Path, err)
+		}
+		return nil
 	}
 
+	log.Printf("Moving to trash: %s (%s)", item.Path, detail)
+	if err := c.driveService.TrashFile(ctx, item.ID); err != nil {
+		return fmt.Errorf("failed to trash '%s': %v", item.Path, err)
+	}
 	return nil
 }
 
 // matchesDatePattern checks if a folder name matches the date pattern
 func (c *CleanupService) matchesDatePattern(name string) (bool, time.Time) {
-	goPattern := c.parseDatePattern(c.datePattern)
-	if goPattern == "" {
+	pattern, err := c.pattern()
+	if err != nil {
 		return false, time.Time{}
 	}
 
-	parsedDate, err := time.Parse(goPattern, name)
-	if err != nil {
+	parsedDate, ok := pattern.Match(name)
+	if !ok {
 		return false, time.Time{}
 	}
 
 	return true, parsedDate
 }
 
-// parseDatePattern converts user-friendly date patterns to Go time format
+// pattern returns the compiled --match-regex, or --match pattern if no regex is set
+func (c *CleanupService) pattern() (*DatePattern, error) {
+	if c.compiled != nil {
+		return c.compiled, nil
+	}
+
+	var err error
+	if c.matchRegex != "" {
+		c.compiled, err = CompileDateRegexp(c.matchRegex)
+	} else {
+		c.compiled, err = CompileDatePattern(c.datePattern)
+	}
+	return c.compiled, err
+}
+
+// parseDatePattern converts a user-friendly date pattern to the equivalent Go time layout.
+// Quoted literals are copied as-is. Week numbers (ww) have no Go equivalent, so
+// patterns using them fall back to yyyy-MM-dd.
 func (c *CleanupService) parseDatePattern(pattern string) string {
-	// Replace longer patterns first to avoid incorrect substring replacements
-	// For example, "yyyy" must be replaced before "yy"
-	result := pattern
-	result = strings.ReplaceAll(result, "yyyy", "2006")
-	result = strings.ReplaceAll(result, "yy", "06")
-	result = strings.ReplaceAll(result, "MM", "01")
-	result = strings.ReplaceAll(result, "dd", "02")
-
-	return result
+	elements, err := tokenizePattern(pattern)
+	if err != nil {
+		return ""
+	}
+
+	var result strings.Builder
+	for _, e := range elements {
+		if e.field == nil {
+			result.WriteString(e.literal)
+			continue
+		}
+		if e.field.goLayout == "" {
+			return "2006-01-02"
+		}
+		result.WriteString(e.field.goLayout)
+	}
+
+	return result.String()
+}
+
+// formatDate formats a parsed date for log messages, using the layout of the --match pattern when possible
+func (c *CleanupService) formatDate(date time.Time) string {
+	layout := "2006-01-02"
+	if c.usesDriveTime() {
+		layout = "2006-01-02 15:04:05"
+	} else if c.matchRegex == "" {
+		if l := c.parseDatePattern(c.datePattern); l != "" {
+			layout = l
+		}
+	}
+	return date.Format(layout)
 }
@@ -31,6 +31,11 @@ func TestParseDatePattern(t *testing.T) {
 			pattern:  "dd-MM-yyyy",
 			expected: "02-01-2006",
 		},
+		{
+			name:     "quoted literal with time",
+			pattern:  "'snap_'yyyyMMdd_HHmmss",
+			expected: "snap_20060102_150405",
+		},
 	}
 
 	for _, tt := range tests {
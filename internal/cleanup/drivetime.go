@@ -0,0 +1,123 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// usesDriveTime reports whether items are dated by a Drive timestamp instead of their name
+func (c *CleanupService) usesDriveTime() bool {
+	return c.dateSource == DateFromCreated || c.dateSource == DateFromModified
+}
+
+// dateSourceName returns the date source, for plans and log messages
+func (c *CleanupService) dateSourceName() string {
+	if c.usesDriveTime() {
+		return c.dateSource
+	}
+	return DateFromName
+}
+
+// driveTime returns the Drive timestamp used to rank an item
+func (c *CleanupService) driveTime(file *drive.File) (time.Time, bool) {
+	value := file.ModifiedTime
+	if c.dateSource == DateFromCreated {
+		value = file.CreatedTime
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
+// nameFilter returns the --match-regex or --match pattern used as a name
+// filter, or nil if neither is set and every item is a candidate
+func (c *CleanupService) nameFilter() (*regexp.Regexp, error) {
+	if c.matchRegex != "" {
+		filter, err := regexp.Compile(c.matchRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s': %v", c.matchRegex, err)
+		}
+		return filter, nil
+	}
+	if c.datePattern != "" {
+		return CompileNameFilter(c.datePattern)
+	}
+	return nil, nil
+}
+
+// traverseByDriveTime applies the retention policy to the children of
+// folderID that pass the name filter, ranked by their Drive time. In folder
+// mode, child folders that do not pass the filter are traversed recursively;
+// in file mode, all child folders are. Folders on the way to a path include
+// glob are only traversed.
+func (c *CleanupService) traverseByDriveTime(ctx context.Context, folderID string, currentPath string, depth int) ([]PlanItem, error) {
+	filter, err := c.nameFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := c.driveService.ListFolders(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders in '%s': %v", currentPath, err)
+	}
+	files, err := c.driveService.ListFiles(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in '%s': %v", currentPath, err)
+	}
+	if hasKeepMarker(files) {
+		log.Printf("Protected: %s (contains %s)", displayPath(currentPath), KeepMarker)
+		return nil, nil
+	}
+
+	if !c.scope.included(currentPath) {
+		return c.traverseSubfolders(ctx, folders, currentPath, depth)
+	}
+
+	children := folders
+	if c.target == TargetFiles {
+		children = files
+	}
+
+	var candidates []FolderWithDate
+	var others []*drive.File
+	for _, child := range children {
+		if filter != nil && !filter.MatchString(child.Name) {
+			others = append(others, child)
+			continue
+		}
+		t, ok := c.driveTime(child)
+		if !ok {
+			log.Printf("Warning: '%s' has no %s time, skipping", joinPath(currentPath, child.Name), c.dateSource)
+			continue
+		}
+		candidates = append(candidates, FolderWithDate{File: child, Date: t, Size: child.Size})
+	}
+
+	if c.target != TargetFiles {
+		if err := c.computeSizes(ctx, candidates, currentPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var items []PlanItem
+	if len(candidates) > 0 {
+		if items, err = c.applyRetentionPolicy(ctx, candidates, currentPath); err != nil {
+			return nil, err
+		}
+	}
+
+	subfolders := others
+	if c.target == TargetFiles {
+		subfolders = folders
+	}
+	subItems, err := c.traverseSubfolders(ctx, subfolders, currentPath, depth)
+	return append(items, subItems...), err
+}
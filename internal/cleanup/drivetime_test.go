@@ -0,0 +1,89 @@
+package cleanup
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestRunByDriveTime(t *testing.T) {
+	item := func(id string, name string, created string, modified string) *drive.File {
+		return &drive.File{Id: id, Name: name, CreatedTime: created, ModifiedTime: modified}
+	}
+
+	newFake := func() *fakeDriveService {
+		return &fakeDriveService{
+			folders: map[string][]*drive.File{
+				"root": {
+					item("a", "nightly-a", "2025-01-01T10:00:00Z", "2025-03-01T00:00:00Z"),
+					item("b", "nightly-b", "2025-01-03T10:00:00Z", "2025-01-03T10:00:00Z"),
+					item("c", "nightly-c", "2025-01-02T10:00:00Z", "2025-01-02T10:00:00Z"),
+					item("other", "archive", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"),
+				},
+				"other": {
+					item("o1", "nightly-x", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"),
+					item("o2", "nightly-y", "2024-02-01T00:00:00Z", "2024-02-01T00:00:00Z"),
+				},
+			},
+			files: map[string][]*drive.File{
+				"other": {
+					item("f1", "dump-2024-01-01.sql", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"),
+					item("f2", "dump-2024-01-02.sql", "2024-01-02T00:00:00Z", "2024-01-02T00:00:00Z"),
+					item("f3", "notes.txt", "2023-01-01T00:00:00Z", "2023-01-01T00:00:00Z"),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantTrashed []string
+	}{
+		{
+			name: "without a filter all children of the root are ranked",
+			opts: Options{DateSource: DateFromCreated, Retention: RetentionPolicy{Keep: 2}},
+			// archive was created first, so it is dropped along with the oldest nightly
+			wantTrashed: []string{"a", "other"},
+		},
+		{
+			name:        "modified time",
+			opts:        Options{DateSource: DateFromModified, Retention: RetentionPolicy{Keep: 2}},
+			wantTrashed: []string{"c", "other"},
+		},
+		{
+			name:        "name filter selects candidates and other folders are traversed",
+			opts:        Options{DateSource: DateFromCreated, DatePattern: "'nightly-'", Retention: RetentionPolicy{Keep: 1}},
+			wantTrashed: []string{"a", "c", "o1"},
+		},
+		{
+			name:        "regex name filter",
+			opts:        Options{DateSource: DateFromCreated, MatchRegex: `^nightly-[ab]$`, Retention: RetentionPolicy{Keep: 1}},
+			wantTrashed: []string{"a"},
+		},
+		{
+			name:        "files filtered by a date pattern",
+			opts:        Options{DateSource: DateFromCreated, Target: TargetFiles, DatePattern: "yyyy-MM-dd", Retention: RetentionPolicy{Keep: 1}},
+			wantTrashed: []string{"f1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFake()
+			c := NewCleanupService(fake, tt.opts)
+
+			if _, err := c.Run(context.Background(), "root"); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			sort.Strings(fake.trashed)
+			if !reflect.DeepEqual(fake.trashed, tt.wantTrashed) {
+				t.Errorf("trashed = %v, want %v", fake.trashed, tt.wantTrashed)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package cleanup
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/parser"
+	"google.golang.org/api/drive/v3"
+)
+
+// cleanupFiles applies the retention policy to the date-stamped files of the folder at currentPath
+// and returns the files to remove.
+// Files are grouped by service and each group is handled independently.
+func (c *CleanupService) cleanupFiles(ctx context.Context, files []*drive.File, currentPath string) ([]PlanItem, error) {
+	groups := make(map[string][]FolderWithDate)
+	for _, file := range files {
+		group, date, ok := c.parseFileName(file.Name)
+		if !ok {
+			continue
+		}
+		groups[group] = append(groups[group], FolderWithDate{File: file, Date: date, Size: file.Size})
+	}
+
+	// Sort the group names so runs are deterministic
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var items []PlanItem
+	for _, name := range names {
+		groupItems, err := c.applyRetentionPolicy(ctx, groups[name], currentPath)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, groupItems...)
+	}
+
+	return items, nil
+}
+
+// parseFileName extracts the retention group and timestamp from a file name.
+// Names matching a parse rule (see parser.Parser) are grouped by service. Otherwise the date pattern is searched inside the name and
+// the group is the name with the date replaced by "*".
+func (c *CleanupService) parseFileName(name string) (string, time.Time, bool) {
+	if meta, err := c.parseMetadata(name); err == nil {
+		return meta.Service, meta.Timestamp, true
+	}
+
+	return c.findDateInName(name)
+}
+
+// parseMetadata parses name with the configured parse rules, or the built-in convention
+func (c *CleanupService) parseMetadata(name string) (*parser.Metadata, error) {
+	if c.parser != nil {
+		return c.parser.Parse(name)
+	}
+	return parser.ParseFilename(name)
+}
+
+// findDateInName searches the date pattern anywhere in name
+func (c *CleanupService) findDateInName(name string) (string, time.Time, bool) {
+	pattern, err := c.pattern()
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	start, end, date, ok := pattern.Find(name)
+	if !ok {
+		return "", time.Time{}, false
+	}
+
+	return name[:start] + "*" + name[end:], date, true
+}
@@ -0,0 +1,282 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/driveclient"
+	"github.com/eliasferreira/google-drive-uploader/internal/parser"
+	"google.golang.org/api/drive/v3"
+)
+
+// fakeDriveService is an in-memory DriveService keyed by parent folder ID
+type fakeDriveService struct {
+	folders map[string][]*drive.File
+	files   map[string][]*drive.File
+	sizes   map[string]int64
+	trashed []string
+	deleted []string
+}
+
+func (f *fakeDriveService) ListFolders(ctx context.Context, parentID string) ([]*drive.File, error) {
+	return f.visible(f.folders[parentID]), nil
+}
+
+func (f *fakeDriveService) ListFiles(ctx context.Context, parentID string) ([]*drive.File, error) {
+	return f.visible(f.files[parentID]), nil
+}
+
+// visible filters out items that were trashed or deleted, like the Drive API does
+func (f *fakeDriveService) visible(items []*drive.File) []*drive.File {
+	removed := make(map[string]bool)
+	for _, id := range append(f.trashed, f.deleted...) {
+		removed[id] = true
+	}
+
+	var result []*drive.File
+	for _, item := range items {
+		if !removed[item.Id] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func (f *fakeDriveService) FolderSize(ctx context.Context, folderID string) (int64, error) {
+	return f.sizes[folderID], nil
+}
+
+func (f *fakeDriveService) FindOrCreateFolder(ctx context.Context, name string, parentID string) (string, error) {
+	for _, folder := range f.folders[parentID] {
+		if folder.Name == name {
+			return folder.Id, nil
+		}
+	}
+
+	id := parentID + "/" + name
+	f.folders[parentID] = append(f.folders[parentID], &drive.File{Id: id, Name: name})
+	return id, nil
+}
+
+// MoveFile moves an item between the parent maps
+func (f *fakeDriveService) MoveFile(ctx context.Context, fileID string, newParentID string) error {
+	for _, items := range []map[string][]*drive.File{f.folders, f.files} {
+		for parentID, children := range items {
+			for i, item := range children {
+				if item.Id == fileID {
+					items[parentID] = append(children[:i:i], children[i+1:]...)
+					items[newParentID] = append(items[newParentID], item)
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("file %s not found", fileID)
+}
+
+func (f *fakeDriveService) GetFile(ctx context.Context, fileID string) (*drive.File, error) {
+	for _, id := range f.deleted {
+		if id == fileID {
+			return nil, driveclient.ErrNotFound
+		}
+	}
+
+	for _, items := range []map[string][]*drive.File{f.folders, f.files} {
+		for _, children := range items {
+			for _, item := range children {
+				if item.Id == fileID {
+					file := *item
+					for _, id := range f.trashed {
+						file.Trashed = file.Trashed || id == fileID
+					}
+					return &file, nil
+				}
+			}
+		}
+	}
+	return nil, driveclient.ErrNotFound
+}
+
+func (f *fakeDriveService) TrashFile(ctx context.Context, fileID string) error {
+	f.trashed = append(f.trashed, fileID)
+	return nil
+}
+
+func (f *fakeDriveService) UntrashFile(ctx context.Context, fileID string) error {
+	for i, id := range f.trashed {
+		if id == fileID {
+			f.trashed = append(f.trashed[:i], f.trashed[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("file %s is not in trash", fileID)
+}
+
+func (f *fakeDriveService) DeleteFile(ctx context.Context, fileID string) error {
+	f.deleted = append(f.deleted, fileID)
+	return nil
+}
+
+func namedFiles(names ...string) []*drive.File {
+	files := make([]*drive.File, 0, len(names))
+	for _, name := range names {
+		files = append(files, &drive.File{Id: name, Name: name})
+	}
+	return files
+}
+
+func TestParseFileName(t *testing.T) {
+	rules, err := parser.NewParser([]parser.Rule{{
+		Name:       "env",
+		Pattern:    `^(?P<service>[a-z]+)-(?P<env>[a-z]+)_(?P<date>\d{4}-\d{2}-\d{2})\.dump$`,
+		DateLayout: "2006-01-02",
+	}}, parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		pattern   string
+		parser    *parser.Parser
+		fileName  string
+		wantGroup string
+		wantDate  time.Time
+		wantOK    bool
+	}{
+		{
+			name:      "smart organize convention with time",
+			pattern:   "yyyy-MM-dd",
+			fileName:  "svc_backup_20251102_040000.sql.gz",
+			wantGroup: "SVC",
+			wantDate:  time.Date(2025, 11, 2, 4, 0, 0, 0, time.UTC),
+			wantOK:    true,
+		},
+		{
+			name:      "date pattern inside the name",
+			pattern:   "yyyyMMdd",
+			fileName:  "backup-mysql-20251224.tar",
+			wantGroup: "backup-mysql-*.tar",
+			wantDate:  time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC),
+			wantOK:    true,
+		},
+		{
+			name:      "parse rule",
+			pattern:   "yyyyMMdd",
+			parser:    rules,
+			fileName:  "keycloak-prod_2025-12-24.dump",
+			wantGroup: "KEYCLOAK",
+			wantDate:  time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC),
+			wantOK:    true,
+		},
+		{
+			name:     "no date",
+			pattern:  "yyyy-MM-dd",
+			fileName: "README.txt",
+			wantOK:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CleanupService{datePattern: tt.pattern, parser: tt.parser}
+			group, date, ok := c.parseFileName(tt.fileName)
+			if ok != tt.wantOK {
+				t.Fatalf("parseFileName(%s) ok = %v, want %v", tt.fileName, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if group != tt.wantGroup {
+				t.Errorf("parseFileName(%s) group = %s, want %s", tt.fileName, group, tt.wantGroup)
+			}
+			if !date.Equal(tt.wantDate) {
+				t.Errorf("parseFileName(%s) date = %v, want %v", tt.fileName, date, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestRunFileTarget(t *testing.T) {
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root": {{Id: "sub", Name: "sub"}},
+		},
+		files: map[string][]*drive.File{
+			"root": namedFiles(
+				"svc_backup_20251101_040000.sql.gz",
+				"svc_backup_20251102_040000.sql.gz",
+				"svc_backup_20251102_160000.sql.gz",
+				"other_backup_20251101_040000.sql.gz",
+				"notes.txt",
+			),
+			"sub": namedFiles(
+				"db-20251101.tar",
+				"db-20251102.tar",
+			),
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyyMMdd",
+		Target:      TargetFiles,
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+
+	deleted, err := c.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []string{
+		"db-20251101.tar",
+		"svc_backup_20251101_040000.sql.gz",
+		"svc_backup_20251102_040000.sql.gz",
+	}
+	sort.Strings(fake.trashed)
+	if !reflect.DeepEqual(fake.trashed, want) {
+		t.Errorf("trashed = %v, want %v", fake.trashed, want)
+	}
+	if len(deleted) != len(want) {
+		t.Errorf("Run() returned %d paths, want %d", len(deleted), len(want))
+	}
+}
+
+func TestRunFileTarget_Permanent(t *testing.T) {
+	fake := &fakeDriveService{
+		files: map[string][]*drive.File{
+			"root": namedFiles(
+				"svc_backup_20251101_040000.sql.gz",
+				"svc_backup_20251102_040000.sql.gz",
+			),
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyyMMdd",
+		Target:      TargetFiles,
+		Retention:   RetentionPolicy{Keep: 1},
+		Permanent:   true,
+	})
+
+	deleted, err := c.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Expired files are deleted, never moved to trash
+	want := []string{"svc_backup_20251101_040000.sql.gz"}
+	if !reflect.DeepEqual(fake.deleted, want) {
+		t.Errorf("deleted = %v, want %v", fake.deleted, want)
+	}
+	if len(fake.trashed) != 0 {
+		t.Errorf("trashed = %v, want nothing", fake.trashed)
+	}
+	if !reflect.DeepEqual(deleted, want) {
+		t.Errorf("Run() = %v, want %v", deleted, want)
+	}
+}
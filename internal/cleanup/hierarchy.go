@@ -0,0 +1,150 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// dateBranch is an intermediate folder of a date hierarchy, such as the year
+// or month folder of yyyy/MM/dd
+type dateBranch struct {
+	File *drive.File
+	// Path is relative to the parent folder of the hierarchy
+	Path string
+}
+
+// collectHierarchy walks a date hierarchy below folder, which matched the
+// levels before level. Leaves (folders matching the last level) are appended
+// to leaves with the date combined across levels, and the intermediate
+// folders to branches in pre-order.
+func (c *CleanupService) collectHierarchy(ctx context.Context, pattern *DatePattern, folder *drive.File, path string, level int, fields map[string]string, leaves *[]FolderWithDate, branches *[]dateBranch) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// A protected year or month folder protects all the dates below it. Markers
+	// deeper down only protect their own date folder, checked as a leaf.
+	reason, err := c.markerProtection(ctx, folder)
+	if err != nil {
+		return fmt.Errorf("failed to check protection of '%s': %v", path, err)
+	}
+	if reason != "" {
+		log.Printf("Protected: %s (%s)", path, reason)
+		return nil
+	}
+
+	*branches = append(*branches, dateBranch{File: folder, Path: path})
+
+	children, err := c.driveService.ListFolders(ctx, folder.Id)
+	if err != nil {
+		return fmt.Errorf("failed to list folders in '%s': %v", path, err)
+	}
+
+	for _, child := range children {
+		childFields, ok := pattern.MatchLevel(level, child.Name)
+		if !ok {
+			continue
+		}
+
+		combined := make(map[string]string, len(fields)+len(childFields))
+		for k, v := range fields {
+			combined[k] = v
+		}
+		for k, v := range childFields {
+			combined[k] = v
+		}
+
+		childPath := path + "/" + child.Name
+		if level == pattern.Levels()-1 {
+			date, ok := resolveDate(combined)
+			if !ok {
+				continue
+			}
+			*leaves = append(*leaves, FolderWithDate{File: child, Date: date, Path: childPath})
+			continue
+		}
+
+		if err := c.collectHierarchy(ctx, pattern, child, childPath, level+1, combined, leaves, branches); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planEmptyBranches returns the intermediate folders of a date hierarchy that
+// are left empty once the planned items are removed. Only branches that lose
+// at least one descendant are considered, deepest first so a year folder is
+// checked after its months.
+func (c *CleanupService) planEmptyBranches(ctx context.Context, branches []dateBranch, parentPath string, planned []PlanItem) ([]PlanItem, error) {
+	ignore := make(map[string]bool)
+	for _, item := range planned {
+		ignore[item.ID] = true
+	}
+
+	var items []PlanItem
+	for i := len(branches) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fullPath := joinPath(parentPath, branches[i].Path)
+		if !hasPlannedDescendant(fullPath, planned) && !hasPlannedDescendant(fullPath, items) {
+			continue
+		}
+
+		empty, err := c.isEmpty(ctx, branches[i].File.Id, ignore)
+		if err != nil {
+			log.Printf("Warning: Failed to check if '%s' is empty: %v", fullPath, err)
+			continue
+		}
+		if !empty {
+			continue
+		}
+
+		log.Printf("Selected for removal: %s (rule: %s)", fullPath, ReasonEmptyParent)
+		items = append(items, PlanItem{
+			ID:           branches[i].File.Id,
+			Path:         fullPath,
+			Rule:         ReasonEmptyParent,
+			ModifiedTime: branches[i].File.ModifiedTime,
+		})
+		ignore[branches[i].File.Id] = true
+	}
+
+	return items, nil
+}
+
+// hasPlannedDescendant reports whether any planned item lies below folderPath
+func hasPlannedDescendant(folderPath string, items []PlanItem) bool {
+	for _, item := range items {
+		if strings.HasPrefix(item.Path, folderPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether a folder has no children other than those in ignore
+// (the items already planned for removal)
+func (c *CleanupService) isEmpty(ctx context.Context, folderID string, ignore map[string]bool) (bool, error) {
+	folders, err := c.driveService.ListFolders(ctx, folderID)
+	if err != nil {
+		return false, err
+	}
+	files, err := c.driveService.ListFiles(ctx, folderID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, child := range append(folders, files...) {
+		if !ignore[child.Id] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
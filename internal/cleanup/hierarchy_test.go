@@ -0,0 +1,120 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestRunDateHierarchy(t *testing.T) {
+	folder := func(id string, name string) *drive.File {
+		return &drive.File{Id: id, Name: name}
+	}
+
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root":     {folder("svc", "SERVICE")},
+			"svc":      {folder("y2024", "2024"), folder("y2025", "2025"), folder("misc", "misc")},
+			"y2024":    {folder("y2024m12", "12")},
+			"y2024m12": {folder("d20241231", "31")},
+			"y2025":    {folder("y2025m01", "01"), folder("y2025m02", "02")},
+			"y2025m01": {folder("d20250110", "10"), folder("d20250115", "15")},
+			"y2025m02": {folder("d20250201", "01"), folder("d20250230", "30")},
+		},
+		files: map[string][]*drive.File{
+			// A stray file keeps the 2025/01 month folder from being removed
+			"y2025m01": {{Id: "notes", Name: "notes.txt"}},
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyy/MM/dd",
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+
+	deleted, err := c.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// 2025/02/30 is not a valid date, so it is never considered
+	wantTrashed := []string{"d20241231", "d20250110", "d20250115", "y2024", "y2024m12"}
+	sort.Strings(fake.trashed)
+	if !reflect.DeepEqual(fake.trashed, wantTrashed) {
+		t.Errorf("trashed = %v, want %v", fake.trashed, wantTrashed)
+	}
+
+	wantDeleted := []string{
+		"SERVICE/2025/01/15",
+		"SERVICE/2025/01/10",
+		"SERVICE/2024/12/31",
+		"SERVICE/2024/12",
+		"SERVICE/2024",
+	}
+	if !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("Run() = %v, want %v", deleted, wantDeleted)
+	}
+}
+
+// removeFailingDriveService fails to trash, delete or move one item
+type removeFailingDriveService struct {
+	*fakeDriveService
+	failID string
+}
+
+func (f *removeFailingDriveService) TrashFile(ctx context.Context, fileID string) error {
+	if fileID == f.failID {
+		return errors.New("rate limited")
+	}
+	return f.fakeDriveService.TrashFile(ctx, fileID)
+}
+
+func (f *removeFailingDriveService) DeleteFile(ctx context.Context, fileID string) error {
+	if fileID == f.failID {
+		return errors.New("rate limited")
+	}
+	return f.fakeDriveService.DeleteFile(ctx, fileID)
+}
+
+func (f *removeFailingDriveService) MoveFile(ctx context.Context, fileID string, newParentID string) error {
+	if fileID == f.failID {
+		return errors.New("rate limited")
+	}
+	return f.fakeDriveService.MoveFile(ctx, fileID, newParentID)
+}
+
+func TestRunDateHierarchy_RemoveFailed(t *testing.T) {
+	folder := func(id string, name string) *drive.File {
+		return &drive.File{Id: id, Name: name}
+	}
+
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root":     {folder("svc", "SERVICE")},
+			"svc":      {folder("y2024", "2024"), folder("y2025", "2025")},
+			"y2024":    {folder("y2024m12", "12")},
+			"y2024m12": {folder("d20241231", "31")},
+			"y2025":    {folder("y2025m01", "01")},
+			"y2025m01": {folder("d20250101", "01")},
+		},
+	}
+
+	c := NewCleanupService(&removeFailingDriveService{fakeDriveService: fake, failID: "d20241231"}, Options{
+		DatePattern: "yyyy/MM/dd",
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+
+	deleted, err := c.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The month and year folders still hold the leaf that failed, so they stay
+	if len(deleted) != 0 || len(fake.trashed) != 0 {
+		t.Errorf("Run() = %v, trashed = %v, want nothing", deleted, fake.trashed)
+	}
+}
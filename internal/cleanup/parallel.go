@@ -0,0 +1,67 @@
+package cleanup
+
+import (
+	"context"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// limitedDriveService bounds the number of concurrent Drive requests made
+// while planning, to stay within the API rate limits
+type limitedDriveService struct {
+	DriveService
+	slots chan struct{}
+}
+
+func newLimitedDriveService(driveService DriveService, concurrency int) *limitedDriveService {
+	return &limitedDriveService{
+		DriveService: driveService,
+		slots:        make(chan struct{}, concurrency),
+	}
+}
+
+// acquire waits for a free request slot
+func (l *limitedDriveService) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limitedDriveService) release() {
+	<-l.slots
+}
+
+func (l *limitedDriveService) ListFolders(ctx context.Context, parentID string) ([]*drive.File, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return l.DriveService.ListFolders(ctx, parentID)
+}
+
+func (l *limitedDriveService) ListFiles(ctx context.Context, parentID string) ([]*drive.File, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return l.DriveService.ListFiles(ctx, parentID)
+}
+
+func (l *limitedDriveService) FolderSize(ctx context.Context, folderID string) (int64, error) {
+	if err := l.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer l.release()
+	return l.DriveService.FolderSize(ctx, folderID)
+}
+
+func (l *limitedDriveService) GetFile(ctx context.Context, fileID string) (*drive.File, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer l.release()
+	return l.DriveService.GetFile(ctx, fileID)
+}
@@ -0,0 +1,360 @@
+package cleanup
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Named groups a date pattern (or a raw --match-regex) may capture
+const (
+	groupYear   = "year"
+	groupYear2  = "yy"
+	groupMonth  = "month"
+	groupDay    = "day"
+	groupHour   = "hour"
+	groupMinute = "minute"
+	groupSecond = "second"
+	groupWeek   = "week"
+)
+
+// patternToken is a date field of the user-friendly pattern language
+type patternToken struct {
+	token    string
+	group    string
+	expr     string
+	goLayout string
+}
+
+// patternTokens lists the supported fields, longer tokens first so "yyyy" wins over "yy"
+var patternTokens = []patternToken{
+	{"yyyy", groupYear, `\d{4}`, "2006"},
+	{"yy", groupYear2, `\d{2}`, "06"},
+	{"MM", groupMonth, `\d{2}`, "01"},
+	{"dd", groupDay, `\d{2}`, "02"},
+	{"HH", groupHour, `\d{2}`, "15"},
+	{"mm", groupMinute, `\d{2}`, "04"},
+	{"ss", groupSecond, `\d{2}`, "05"},
+	{"ww", groupWeek, `\d{2}`, ""},
+}
+
+// DatePattern is a compiled --match pattern or --match-regex expression.
+// A pattern with unquoted slashes (yyyy/MM/dd) describes a folder hierarchy,
+// with one level per slash-separated part.
+type DatePattern struct {
+	source string
+	full   *regexp.Regexp
+	find   *regexp.Regexp
+	levels []*regexp.Regexp
+}
+
+// patternElement is either a date field or a literal, as produced by tokenizePattern
+type patternElement struct {
+	field   *patternToken
+	literal string
+}
+
+// tokenizePattern splits a pattern into date fields and literals.
+// Text in single quotes is literal ('W', 'snap_'), and two single quotes are a literal quote.
+// Letters that do not form a known field are literal as well.
+func tokenizePattern(pattern string) ([]patternElement, error) {
+	var elements []patternElement
+	rest := pattern
+
+	for rest != "" {
+		if rest[0] == '\'' {
+			// '' outside a quoted section is an escaped quote
+			if strings.HasPrefix(rest, "''") {
+				elements = append(elements, patternElement{literal: "'"})
+				rest = rest[2:]
+				continue
+			}
+
+			var literal strings.Builder
+			i := 1
+			for {
+				if i >= len(rest) {
+					return nil, fmt.Errorf("unterminated quote in pattern '%s'", pattern)
+				}
+				if rest[i] == '\'' {
+					if i+1 < len(rest) && rest[i+1] == '\'' {
+						literal.WriteByte('\'')
+						i += 2
+						continue
+					}
+					break
+				}
+				literal.WriteByte(rest[i])
+				i++
+			}
+			elements = append(elements, patternElement{literal: literal.String()})
+			rest = rest[i+1:]
+			continue
+		}
+
+		matched := false
+		for i := range patternTokens {
+			if strings.HasPrefix(rest, patternTokens[i].token) {
+				elements = append(elements, patternElement{field: &patternTokens[i]})
+				rest = rest[len(patternTokens[i].token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			elements = append(elements, patternElement{literal: rest[:1]})
+			rest = rest[1:]
+		}
+	}
+
+	return elements, nil
+}
+
+// splitPatternLevels splits a pattern on unquoted slashes, one part per folder level
+func splitPatternLevels(pattern string) []string {
+	var levels []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\'':
+			quoted = !quoted
+		case '/':
+			if !quoted {
+				levels = append(levels, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(levels, pattern[start:])
+}
+
+// CompileDatePattern compiles a user-friendly date pattern such as yyyy-MM-dd,
+// yyyy-'W'ww, yyyy-MM, 'snap_'yyyyMMdd_HHmmss or the hierarchy yyyy/MM/dd
+func CompileDatePattern(pattern string) (*DatePattern, error) {
+	seen := make(map[string]bool)
+	var exprs []string
+	for _, level := range splitPatternLevels(pattern) {
+		elements, err := tokenizePattern(level)
+		if err != nil {
+			return nil, fmt.Errorf("%v in pattern '%s'", err, pattern)
+		}
+
+		var expr strings.Builder
+		for _, e := range elements {
+			if e.field == nil {
+				expr.WriteString(regexp.QuoteMeta(e.literal))
+				continue
+			}
+			if seen[e.field.group] {
+				return nil, fmt.Errorf("field '%s' appears more than once in pattern '%s'", e.field.token, pattern)
+			}
+			seen[e.field.group] = true
+			fmt.Fprintf(&expr, "(?P<%s>%s)", e.field.group, e.field.expr)
+		}
+		if expr.Len() == 0 {
+			return nil, fmt.Errorf("empty folder level in pattern '%s'", pattern)
+		}
+		exprs = append(exprs, expr.String())
+	}
+
+	if !seen[groupYear] && !seen[groupYear2] {
+		return nil, fmt.Errorf("pattern '%s' must contain a year (yyyy or yy)", pattern)
+	}
+	if seen[groupWeek] && (seen[groupMonth] || seen[groupDay]) {
+		return nil, fmt.Errorf("pattern '%s' cannot combine a week (ww) with a month or day", pattern)
+	}
+
+	if len(exprs) == 1 {
+		return newDatePattern(pattern, exprs[0])
+	}
+
+	p := &DatePattern{source: pattern}
+	for _, expr := range exprs {
+		p.levels = append(p.levels, regexp.MustCompile("^(?:"+expr+")$"))
+	}
+	return p, nil
+}
+
+// CompileDateRegexp compiles a raw regular expression with named groups
+// year (required), month, day, hour, minute, second or week
+func CompileDateRegexp(expr string) (*DatePattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex '%s': %v", expr, err)
+	}
+
+	hasYear := false
+	for _, name := range re.SubexpNames() {
+		if name == groupYear || name == groupYear2 {
+			hasYear = true
+		}
+	}
+	if !hasYear {
+		return nil, fmt.Errorf("regex '%s' must have a named group (?P<year>...)", expr)
+	}
+
+	return newDatePattern(expr, expr)
+}
+
+// CompileNameFilter compiles a pattern into an expression matching names that
+// contain it anywhere. Unlike CompileDatePattern, date fields only need to
+// have the right shape and no year is required, so 'nightly-' is a valid filter.
+func CompileNameFilter(pattern string) (*regexp.Regexp, error) {
+	if len(splitPatternLevels(pattern)) > 1 {
+		return nil, fmt.Errorf("folder hierarchy pattern '%s' cannot be used as a name filter", pattern)
+	}
+
+	elements, err := tokenizePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var expr strings.Builder
+	for _, e := range elements {
+		if e.field == nil {
+			expr.WriteString(regexp.QuoteMeta(e.literal))
+			continue
+		}
+		expr.WriteString(e.field.expr)
+	}
+	return regexp.Compile(expr.String())
+}
+
+func newDatePattern(source string, expr string) (*DatePattern, error) {
+	full, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %v", source, err)
+	}
+	return &DatePattern{
+		source: source,
+		full:   full,
+		find:   regexp.MustCompile(expr),
+		levels: []*regexp.Regexp{full},
+	}, nil
+}
+
+// String returns the pattern as given by the user
+func (p *DatePattern) String() string {
+	return p.source
+}
+
+// Levels returns the number of folder levels the pattern spans (1 unless it is a hierarchy)
+func (p *DatePattern) Levels() int {
+	return len(p.levels)
+}
+
+// Match parses name if the whole name matches a single-level pattern
+func (p *DatePattern) Match(name string) (time.Time, bool) {
+	if p.full == nil {
+		return time.Time{}, false
+	}
+	fields, ok := p.MatchLevel(0, name)
+	if !ok {
+		return time.Time{}, false
+	}
+	return resolveDate(fields)
+}
+
+// MatchLevel matches a folder name against one level of the pattern and returns the captured fields
+func (p *DatePattern) MatchLevel(level int, name string) (map[string]string, bool) {
+	re := p.levels[level]
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return nil, false
+	}
+	return captures(re, m), true
+}
+
+// Find searches the pattern anywhere in name and returns the position and date of the first valid match.
+// Hierarchical patterns never match inside a single name.
+func (p *DatePattern) Find(name string) (start int, end int, date time.Time, ok bool) {
+	if p.find == nil {
+		return 0, 0, time.Time{}, false
+	}
+	for _, loc := range p.find.FindAllStringSubmatchIndex(name, -1) {
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = name[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		if date, ok := resolveDate(captures(p.find, m)); ok {
+			return loc[0], loc[1], date, true
+		}
+	}
+	return 0, 0, time.Time{}, false
+}
+
+// captures returns the non-empty named groups of a match
+func captures(re *regexp.Regexp, match []string) map[string]string {
+	fields := make(map[string]string)
+	for i, name := range re.SubexpNames() {
+		if i != 0 && name != "" && match[i] != "" {
+			fields[name] = match[i]
+		}
+	}
+	return fields
+}
+
+// resolveDate builds a calendar-validated time from captured fields
+func resolveDate(captured map[string]string) (time.Time, bool) {
+	fields := map[string]int{groupMonth: 1, groupDay: 1}
+	present := make(map[string]bool)
+	for name, value := range captured {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return time.Time{}, false
+		}
+		fields[name] = n
+		present[name] = true
+	}
+
+	year := fields[groupYear]
+	if !present[groupYear] {
+		if !present[groupYear2] {
+			return time.Time{}, false
+		}
+		// Same pivot as Go's time package: 69-99 is 19xx, 00-68 is 20xx
+		year = 2000 + fields[groupYear2]
+		if fields[groupYear2] >= 69 {
+			year = 1900 + fields[groupYear2]
+		}
+	}
+
+	if present[groupWeek] {
+		return isoWeekStart(year, fields[groupWeek])
+	}
+
+	month, day := fields[groupMonth], fields[groupDay]
+	hour, minute, second := fields[groupHour], fields[groupMinute], fields[groupSecond]
+	if month < 1 || month > 12 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC)
+	// time.Date normalizes overflow (e.g. Feb 30 -> Mar 2), so reject anything that moved
+	if t.Month() != time.Month(month) || t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// isoWeekStart returns the Monday of the given ISO week
+func isoWeekStart(year int, week int) (time.Time, bool) {
+	if week < 1 || week > 53 {
+		return time.Time{}, false
+	}
+
+	// January 4th is always in ISO week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	t := jan4.AddDate(0, 0, -offset+(week-1)*7)
+
+	if y, w := t.ISOWeek(); y != year || w != week {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,76 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatePatternMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		input     string
+		wantMatch bool
+		wantDate  time.Time
+	}{
+		{"quoted literal prefix", "'snap_'yyyyMMdd", "snap_20251224", true, time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)},
+		{"literal containing a token", "'backup-dd-'yyyy-MM-dd", "backup-dd-2025-12-24", true, time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)},
+		{"unquoted letters are literal", "backup-yyyy-MM-dd", "backup-2025-12-24", true, time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)},
+		{"escaped quote", "yyyy''MM", "2025'12", true, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"time of day", "yyyyMMdd_HHmmss", "20251224_084205", true, time.Date(2025, 12, 24, 8, 42, 5, 0, time.UTC)},
+		{"month only", "yyyy-MM", "2025-12", true, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"iso week", "yyyy-'W'ww", "2025-W01", true, time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)},
+		{"two digit year", "yyMMdd", "251224", true, time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)},
+		{"suffix", "yyyy-MM-dd'.daily'", "2025-12-24.daily", true, time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)},
+		{"regex metacharacters are literal", "yyyy.MM.dd", "2025x12x24", false, time.Time{}},
+		{"impossible date", "yyyy-MM-dd", "2025-02-30", false, time.Time{}},
+		{"impossible hour", "yyyyMMdd_HHmmss", "20251224_250000", false, time.Time{}},
+		{"impossible week", "yyyy-'W'ww", "2025-W54", false, time.Time{}},
+		{"partial match", "yyyy-MM", "2025-12-24", false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := CompileDatePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("CompileDatePattern(%s) error = %v", tt.pattern, err)
+			}
+			date, ok := p.Match(tt.input)
+			if ok != tt.wantMatch {
+				t.Fatalf("Match(%s) = %v, want %v", tt.input, ok, tt.wantMatch)
+			}
+			if ok && !date.Equal(tt.wantDate) {
+				t.Errorf("Match(%s) date = %v, want %v", tt.input, date, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestCompileDatePatternErrors(t *testing.T) {
+	for _, pattern := range []string{"MM-dd", "'unterminated yyyy", "yyyy-yyyy", "yyyy-'W'ww-dd"} {
+		if _, err := CompileDatePattern(pattern); err == nil {
+			t.Errorf("CompileDatePattern(%s) error = nil, want error", pattern)
+		}
+	}
+}
+
+func TestCompileDateRegexp(t *testing.T) {
+	p, err := CompileDateRegexp(`db_(?P<day>\d{2})\.(?P<month>\d{2})\.(?P<year>\d{4})`)
+	if err != nil {
+		t.Fatalf("CompileDateRegexp() error = %v", err)
+	}
+
+	date, ok := p.Match("db_24.12.2025")
+	if !ok || !date.Equal(time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Match() = %v, %v, want 2025-12-24", date, ok)
+	}
+
+	start, end, _, ok := p.Find("prod-db_24.12.2025.tar")
+	if !ok || start != 5 || end != 18 {
+		t.Errorf("Find() = %d, %d, %v, want 5, 18, true", start, end, ok)
+	}
+
+	if _, err := CompileDateRegexp(`(?P<month>\d{2})`); err == nil {
+		t.Error("CompileDateRegexp() without year group error = nil, want error")
+	}
+}
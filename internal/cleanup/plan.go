@@ -0,0 +1,128 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// planVersion is the version of the plan file format
+const planVersion = 1
+
+// folderMimeType is the MIME type of Drive folders
+const folderMimeType = "application/vnd.google-apps.folder"
+
+// PlanItem is an item selected for removal
+type PlanItem struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	// Date is the date parsed from the name, zero for empty hierarchy folders
+	Date time.Time `json:"date,omitzero"`
+	// Rule is why the item was selected, e.g. ReasonExpired
+	Rule string `json:"rule"`
+	// ModifiedTime is the Drive modifiedTime when the plan was made, used to detect changes before applying
+	ModifiedTime string `json:"modified_time,omitempty"`
+}
+
+// Plan is the reviewable list of items a cleanup removes
+type Plan struct {
+	Version      int        `json:"version"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RootFolderID string     `json:"root_folder_id"`
+	Target       string     `json:"target"`
+	DateSource   string     `json:"date_source"`
+	Pattern      string     `json:"pattern"`
+	Retention    string     `json:"retention"`
+	Permanent    bool       `json:"permanent"`
+	Action       string     `json:"action"`
+	ArchiveID    string     `json:"archive_folder_id,omitempty"`
+	Items        []PlanItem `json:"items"`
+	// Errors lists the folders that could not be examined, so the plan may be incomplete
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Outcome describes what applying the plan does to its items, e.g. "moved to trash"
+func (p *Plan) Outcome() string {
+	switch {
+	case p.Action == ActionArchive:
+		return "archived"
+	case p.Permanent:
+		return "permanently deleted"
+	default:
+		return "moved to trash"
+	}
+}
+
+// SavePlan writes a plan as indented JSON so it can be reviewed
+func SavePlan(path string, plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode plan: %v", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write plan: %v", err)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan written by SavePlan
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read plan: %v", err)
+	}
+
+	plan := &Plan{}
+	if err := json.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("unable to parse plan: %v", err)
+	}
+	if plan.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d (expected %d)", plan.Version, planVersion)
+	}
+	return plan, nil
+}
+
+// verifyItem checks that a planned item is unchanged since the plan was made.
+// Empty hierarchy folders are checked for emptiness instead, since removing
+// their children may update them.
+func (c *CleanupService) verifyItem(ctx context.Context, item PlanItem) error {
+	file, err := c.driveService.GetFile(ctx, item.ID)
+	if err != nil {
+		return fmt.Errorf("unable to check item: %v", err)
+	}
+	if file.Trashed {
+		return fmt.Errorf("already in trash")
+	}
+
+	// The item may have been protected after the plan was reviewed
+	if file.MimeType == folderMimeType {
+		reason, err := c.folderProtection(ctx, file)
+		if err != nil {
+			return fmt.Errorf("unable to check item: %v", err)
+		}
+		if reason != "" {
+			return fmt.Errorf("protected (%s)", reason)
+		}
+	} else if isPinned(file) {
+		return fmt.Errorf("protected (%s)", PinnedProperty)
+	}
+
+	if item.Rule == ReasonEmptyParent {
+		empty, err := c.isEmpty(ctx, item.ID, nil)
+		if err != nil {
+			return fmt.Errorf("unable to check item: %v", err)
+		}
+		if !empty {
+			return fmt.Errorf("folder is no longer empty")
+		}
+		return nil
+	}
+
+	if file.ModifiedTime != item.ModifiedTime {
+		return fmt.Errorf("modified since the plan was made (%s, planned %s)", file.ModifiedTime, item.ModifiedTime)
+	}
+	return nil
+}
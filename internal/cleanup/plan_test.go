@@ -0,0 +1,168 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestPlanAndApply(t *testing.T) {
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root": {
+				{Id: "d1", Name: "2025-01-01", ModifiedTime: "2025-01-01T00:00:00Z"},
+				{Id: "d2", Name: "2025-01-02", ModifiedTime: "2025-01-02T00:00:00Z"},
+				{Id: "d3", Name: "2025-01-03", ModifiedTime: "2025-01-03T00:00:00Z"},
+			},
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyy-MM-dd",
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+
+	plan, err := c.Plan(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(fake.trashed) != 0 {
+		t.Fatalf("Plan() trashed %v, want nothing", fake.trashed)
+	}
+
+	wantPaths := []string{"2025-01-02", "2025-01-01"}
+	var gotPaths []string
+	for _, item := range plan.Items {
+		gotPaths = append(gotPaths, item.Path)
+		if item.Rule != ReasonExpired {
+			t.Errorf("item %s rule = %s, want %s", item.Path, item.Rule, ReasonExpired)
+		}
+	}
+	if !reflect.DeepEqual(gotPaths, wantPaths) {
+		t.Fatalf("plan paths = %v, want %v", gotPaths, wantPaths)
+	}
+
+	// Round-trip through a plan file, as "cleanup plan" and "cleanup apply" do
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := SavePlan(path, plan); err != nil {
+		t.Fatalf("SavePlan() error = %v", err)
+	}
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Items, plan.Items) {
+		t.Errorf("LoadPlan() items = %v, want %v", loaded.Items, plan.Items)
+	}
+
+	// d1 changes after the plan was reviewed, so apply must skip it
+	fake.folders["root"][0].ModifiedTime = "2025-02-01T00:00:00Z"
+
+	removed, err := c.Apply(context.Background(), loaded, true)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"2025-01-02"}) {
+		t.Errorf("Apply() = %v, want [2025-01-02]", removed)
+	}
+	if !reflect.DeepEqual(fake.trashed, []string{"d2"}) {
+		t.Errorf("trashed = %v, want [d2]", fake.trashed)
+	}
+}
+
+func TestPlanAndApply_Permanent(t *testing.T) {
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root": {
+				{Id: "d1", Name: "2025-01-01", ModifiedTime: "2025-01-01T00:00:00Z"},
+				{Id: "d2", Name: "2025-01-02", ModifiedTime: "2025-01-02T00:00:00Z"},
+			},
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyy-MM-dd",
+		Retention:   RetentionPolicy{Keep: 1},
+		Permanent:   true,
+	})
+
+	plan, err := c.Plan(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if !plan.Permanent {
+		t.Fatal("plan is not marked permanent")
+	}
+
+	removed, err := c.Apply(context.Background(), plan, true)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"2025-01-01"}) {
+		t.Errorf("Apply() = %v, want [2025-01-01]", removed)
+	}
+	if !reflect.DeepEqual(fake.deleted, []string{"d1"}) || len(fake.trashed) != 0 {
+		t.Errorf("deleted = %v, trashed = %v, want [d1] deleted and nothing trashed", fake.deleted, fake.trashed)
+	}
+
+	// The run log records the deletion as permanent, so it cannot be undone
+	run := c.LastRun()
+	if !run.Permanent {
+		t.Fatal("run log is not marked permanent")
+	}
+	if _, err := c.Undo(context.Background(), run); err == nil {
+		t.Error("Expected error undoing a permanent run, got nil")
+	}
+}
+
+// cancelingDriveService cancels the run once it has trashed an item, like a signal arriving mid-apply
+type cancelingDriveService struct {
+	*fakeDriveService
+	cancel context.CancelFunc
+}
+
+func (f *cancelingDriveService) TrashFile(ctx context.Context, fileID string) error {
+	err := f.fakeDriveService.TrashFile(ctx, fileID)
+	f.cancel()
+	return err
+}
+
+func TestApply_Cancelled(t *testing.T) {
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root": {
+				{Id: "d1", Name: "2025-01-01"},
+				{Id: "d2", Name: "2025-01-02"},
+				{Id: "d3", Name: "2025-01-03"},
+				{Id: "d4", Name: "2025-01-04"},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCleanupService(&cancelingDriveService{fakeDriveService: fake, cancel: cancel}, Options{
+		DatePattern: "yyyy-MM-dd",
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+
+	plan, err := c.Plan(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	removed, err := c.Apply(ctx, plan, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Apply() error = %v, want context.Canceled", err)
+	}
+	if !reflect.DeepEqual(removed, []string{"2025-01-03"}) {
+		t.Errorf("Apply() removed = %v, want only the item trashed before cancellation", removed)
+	}
+	if !reflect.DeepEqual(fake.trashed, []string{"d3"}) {
+		t.Errorf("trashed = %v, want [d3]", fake.trashed)
+	}
+}
@@ -0,0 +1,218 @@
+package cleanup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Retention rule names reported for each kept folder
+const (
+	RuleLast    = "last"
+	RuleDaily   = "daily"
+	RuleWeekly  = "weekly"
+	RuleMonthly = "monthly"
+	RuleYearly  = "yearly"
+	RuleRecent  = "recent"
+	RuleSize    = "size"
+)
+
+// Reasons an item is selected for removal
+const (
+	// ReasonExpired means no retention rule kept the item
+	ReasonExpired = "expired"
+	// ReasonOverBudget means the item was dropped to fit the size budget
+	ReasonOverBudget = "over-size-budget"
+	// ReasonEmptyParent means the item is a date hierarchy folder left empty after pruning
+	ReasonEmptyParent = "empty-parent"
+)
+
+// now is the clock used for age-based retention, replaceable in tests
+var now = time.Now
+
+// RetentionPolicy describes which date folders are kept in each group.
+// Keep retains the N most recent folders, the other fields implement
+// grandfather-father-son retention: the newest folder of each of the last N
+// days, ISO weeks, months and years is kept. A folder kept by no rule is trashed.
+//
+// When OlderThan is set, folders dated within OlderThan of now are kept as
+// well, and Keep acts as a floor so a group that stopped receiving backups
+// never loses its last copies.
+//
+// When MaxSize is set, it is a byte budget for each group: the oldest kept
+// folders are dropped until the group fits, but never the Keep most recent.
+// On its own (without daily/weekly/monthly/yearly or OlderThan rules),
+// MaxSize keeps as many of the newest folders as fit in the budget.
+type RetentionPolicy struct {
+	Keep        int
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+	KeepYearly  int
+	OlderThan   time.Duration
+	MaxSize     int64
+}
+
+// String returns a human-readable summary of the policy
+func (p RetentionPolicy) String() string {
+	parts := []string{fmt.Sprintf("last %d", p.Keep)}
+	if p.KeepDaily > 0 {
+		parts = append(parts, fmt.Sprintf("daily %d", p.KeepDaily))
+	}
+	if p.KeepWeekly > 0 {
+		parts = append(parts, fmt.Sprintf("weekly %d", p.KeepWeekly))
+	}
+	if p.KeepMonthly > 0 {
+		parts = append(parts, fmt.Sprintf("monthly %d", p.KeepMonthly))
+	}
+	if p.KeepYearly > 0 {
+		parts = append(parts, fmt.Sprintf("yearly %d", p.KeepYearly))
+	}
+	if p.OlderThan > 0 {
+		parts = append(parts, fmt.Sprintf("trash older than %s", p.OlderThan))
+	}
+	if p.MaxSize > 0 {
+		parts = append(parts, fmt.Sprintf("max size %s", FormatSize(p.MaxSize)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Apply sorts folders by date descending (most recent first) and returns,
+// for each index of the sorted slice that is kept, the rules that kept it.
+func (p RetentionPolicy) Apply(folders []FolderWithDate) map[int][]string {
+	kept, _ := p.Evaluate(folders)
+	return kept
+}
+
+// Evaluate is like Apply and also returns, for each index that is not kept,
+// the reason it expired (ReasonExpired or ReasonOverBudget).
+func (p RetentionPolicy) Evaluate(folders []FolderWithDate) (map[int][]string, map[int]string) {
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].Date.After(folders[j].Date)
+	})
+
+	kept := make(map[int][]string)
+	for i := 0; i < p.Keep && i < len(folders); i++ {
+		kept[i] = append(kept[i], RuleLast)
+	}
+
+	if p.OlderThan > 0 {
+		cutoff := now().Add(-p.OlderThan)
+		for i := range folders {
+			if !folders[i].Date.Before(cutoff) {
+				kept[i] = append(kept[i], RuleRecent)
+			}
+		}
+	}
+
+	p.applyPeriod(folders, kept, RuleDaily, p.KeepDaily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	p.applyPeriod(folders, kept, RuleWeekly, p.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	p.applyPeriod(folders, kept, RuleMonthly, p.KeepMonthly, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
+	p.applyPeriod(folders, kept, RuleYearly, p.KeepYearly, func(t time.Time) string {
+		return t.Format("2006")
+	})
+
+	var overBudget []int
+	if p.MaxSize > 0 {
+		overBudget = p.applyBudget(folders, kept)
+	}
+
+	expired := make(map[int]string)
+	for i := range folders {
+		if _, ok := kept[i]; ok {
+			continue
+		}
+		// Without date rules, only the budget decides what is kept
+		if p.MaxSize > 0 && !p.selectsByDate() {
+			expired[i] = ReasonOverBudget
+		} else {
+			expired[i] = ReasonExpired
+		}
+	}
+	for _, i := range overBudget {
+		expired[i] = ReasonOverBudget
+	}
+
+	return kept, expired
+}
+
+// UsesSize reports whether the policy needs folder sizes
+func (p RetentionPolicy) UsesSize() bool {
+	return p.MaxSize > 0
+}
+
+// selectsByDate reports whether any calendar or age rule is configured
+func (p RetentionPolicy) selectsByDate() bool {
+	return p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.KeepYearly > 0 || p.OlderThan > 0
+}
+
+// applyBudget enforces MaxSize on the kept folders, dropping the oldest first,
+// and returns the indexes it dropped. folders must already be sorted most recent first.
+func (p RetentionPolicy) applyBudget(folders []FolderWithDate, kept map[int][]string) []int {
+	var total int64
+
+	// Without date rules the budget itself selects folders: keep the newest that fit
+	if !p.selectsByDate() {
+		for i := range folders {
+			total += folders[i].Size
+			if total > p.MaxSize {
+				break
+			}
+			kept[i] = append(kept[i], RuleSize)
+		}
+	}
+
+	total = 0
+	for i := range kept {
+		total += folders[i].Size
+	}
+
+	// Drop the oldest kept folders until the group fits, never touching the Keep most recent
+	var dropped []int
+	for i := len(folders) - 1; i >= p.Keep && total > p.MaxSize; i-- {
+		if _, ok := kept[i]; ok {
+			delete(kept, i)
+			total -= folders[i].Size
+			dropped = append(dropped, i)
+		}
+	}
+	return dropped
+}
+
+// FormatSize returns a human-readable binary size such as "1.5 GB"
+func FormatSize(bytes int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(bytes)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// applyPeriod keeps the newest folder of each of the last count periods.
+// folders must already be sorted most recent first.
+func (p RetentionPolicy) applyPeriod(folders []FolderWithDate, kept map[int][]string, rule string, count int, period func(time.Time) string) {
+	lastPeriod := ""
+	for i := 0; i < len(folders) && count > 0; i++ {
+		current := period(folders[i].Date)
+		if current == lastPeriod {
+			continue
+		}
+		lastPeriod = current
+		kept[i] = append(kept[i], rule)
+		count--
+	}
+}
@@ -0,0 +1,164 @@
+package cleanup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func foldersForDates(dates ...string) []FolderWithDate {
+	folders := make([]FolderWithDate, 0, len(dates))
+	for _, d := range dates {
+		parsed, _ := time.Parse("2006-01-02", d)
+		folders = append(folders, FolderWithDate{File: &drive.File{Id: d, Name: d}, Date: parsed})
+	}
+	return folders
+}
+
+// keptNames maps the kept folder names to the rules that kept them
+func keptNames(folders []FolderWithDate, kept map[int][]string) map[string][]string {
+	result := make(map[string][]string)
+	for i, rules := range kept {
+		result[folders[i].File.Name] = rules
+	}
+	return result
+}
+
+func TestRetentionPolicyApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetentionPolicy
+		folders []FolderWithDate
+		want    map[string][]string
+	}{
+		{
+			name:    "keep last only",
+			policy:  RetentionPolicy{Keep: 2},
+			folders: foldersForDates("2025-01-10", "2025-01-20", "2025-01-15"),
+			want: map[string][]string{
+				"2025-01-20": {RuleLast},
+				"2025-01-15": {RuleLast},
+			},
+		},
+		{
+			name:    "daily keeps one folder per day",
+			policy:  RetentionPolicy{KeepDaily: 2},
+			folders: foldersForDates("2025-01-01", "2025-01-02", "2025-01-03"),
+			want: map[string][]string{
+				"2025-01-03": {RuleDaily},
+				"2025-01-02": {RuleDaily},
+			},
+		},
+		{
+			name:   "weekly uses ISO weeks",
+			policy: RetentionPolicy{KeepWeekly: 2},
+			// 2025-01-05 is a Sunday (ISO week 1), 2025-01-06 is a Monday (ISO week 2)
+			folders: foldersForDates("2025-01-04", "2025-01-05", "2025-01-06", "2025-01-07"),
+			want: map[string][]string{
+				"2025-01-07": {RuleWeekly},
+				"2025-01-05": {RuleWeekly},
+			},
+		},
+		{
+			name:    "combined rules report every rule that kept a folder",
+			policy:  RetentionPolicy{Keep: 1, KeepDaily: 1, KeepMonthly: 2, KeepYearly: 2},
+			folders: foldersForDates("2024-12-31", "2025-01-15", "2025-02-01", "2025-02-10"),
+			want: map[string][]string{
+				"2025-02-10": {RuleLast, RuleDaily, RuleMonthly, RuleYearly},
+				"2025-01-15": {RuleMonthly},
+				"2024-12-31": {RuleYearly},
+			},
+		},
+		{
+			name:    "older than trashes folders before the cutoff",
+			policy:  RetentionPolicy{Keep: 1, OlderThan: 30 * 24 * time.Hour},
+			folders: foldersForDates("2025-05-01", "2025-05-25", "2025-06-01"),
+			want: map[string][]string{
+				"2025-06-01": {RuleLast, RuleRecent},
+				"2025-05-25": {RuleRecent},
+			},
+		},
+		{
+			name:    "keep is a floor when every folder is older than the cutoff",
+			policy:  RetentionPolicy{Keep: 2, OlderThan: 30 * 24 * time.Hour},
+			folders: foldersForDates("2024-01-01", "2024-02-01", "2024-03-01"),
+			want: map[string][]string{
+				"2024-03-01": {RuleLast},
+				"2024-02-01": {RuleLast},
+			},
+		},
+	}
+
+	originalNow := now
+	now = func() time.Time { return time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = originalNow }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept := tt.policy.Apply(tt.folders)
+			got := keptNames(tt.folders, kept)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() kept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetentionPolicyMaxSize(t *testing.T) {
+	const gb = int64(1 << 30)
+
+	sized := func(sizes ...int64) []FolderWithDate {
+		folders := foldersForDates("2025-01-05", "2025-01-04", "2025-01-03", "2025-01-02")
+		for i := range folders {
+			folders[i].Size = sizes[i]
+		}
+		return folders
+	}
+
+	tests := []struct {
+		name    string
+		policy  RetentionPolicy
+		folders []FolderWithDate
+		want    map[string][]string
+	}{
+		{
+			name:    "keeps the newest folders that fit",
+			policy:  RetentionPolicy{Keep: 1, MaxSize: 25 * gb},
+			folders: sized(10*gb, 10*gb, 10*gb, 1*gb),
+			want: map[string][]string{
+				"2025-01-05": {RuleLast, RuleSize},
+				"2025-01-04": {RuleSize},
+			},
+		},
+		{
+			name:    "never drops below keep",
+			policy:  RetentionPolicy{Keep: 2, MaxSize: 5 * gb},
+			folders: sized(10*gb, 10*gb, 1*gb, 1*gb),
+			want: map[string][]string{
+				"2025-01-05": {RuleLast},
+				"2025-01-04": {RuleLast},
+			},
+		},
+		{
+			name:    "caps date rules by trashing the oldest first",
+			policy:  RetentionPolicy{Keep: 1, KeepDaily: 4, MaxSize: 25 * gb},
+			folders: sized(10*gb, 10*gb, 10*gb, 1*gb),
+			want: map[string][]string{
+				"2025-01-05": {RuleLast, RuleDaily},
+				"2025-01-04": {RuleDaily},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept := tt.policy.Apply(tt.folders)
+			got := keptNames(tt.folders, kept)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() kept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
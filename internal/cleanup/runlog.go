@@ -0,0 +1,251 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliasferreira/google-drive-uploader/internal/driveclient"
+)
+
+// runIDLayout formats run IDs so they sort chronologically
+const runIDLayout = "20060102T150405Z"
+
+// RunLog records what a cleanup run removed, so it can be undone
+type RunLog struct {
+	ID           string        `json:"id"`
+	StartedAt    time.Time     `json:"started_at"`
+	RootFolderID string        `json:"root_folder_id"`
+	Permanent    bool          `json:"permanent"`
+	Action       string        `json:"action"`
+	Items        []RunLogEntry `json:"items"`
+}
+
+// RunLogEntry is an item removed by a cleanup run
+type RunLogEntry struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	RemovedAt time.Time `json:"removed_at"`
+}
+
+// LastRun returns the log of the last Run or Apply
+func (c *CleanupService) LastRun() *RunLog {
+	return c.run
+}
+
+// record adds a removed item to the log of the current run
+func (c *CleanupService) record(item PlanItem) {
+	c.run.Items = append(c.run.Items, RunLogEntry{
+		ID:        item.ID,
+		Path:      item.Path,
+		RemovedAt: now().UTC(),
+	})
+}
+
+// newRunLog starts the log of a run applying plan
+func newRunLog(plan *Plan) *RunLog {
+	started := now().UTC()
+	return &RunLog{
+		ID:           started.Format(runIDLayout),
+		StartedAt:    started,
+		RootFolderID: plan.RootFolderID,
+		Permanent:    plan.Permanent,
+		Action:       plan.Action,
+		Items:        make([]RunLogEntry, 0),
+	}
+}
+
+// SaveRunLog writes a run log to dir as <run id>.json and returns its path.
+// Run IDs have a resolution of one second, so if another run of the same
+// second was already logged, a -2, -3, ... suffix is added to run.ID.
+func SaveRunLog(dir string, run *RunLog) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("unable to create run log directory: %v", err)
+	}
+
+	base := run.ID
+	for n := 2; ; n++ {
+		path := filepath.Join(dir, run.ID+".json")
+		// O_EXCL fails instead of overwriting the log of another run
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			run.ID = fmt.Sprintf("%s-%d", base, n)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("unable to write run log: %v", err)
+		}
+
+		data, err := json.MarshalIndent(run, "", "  ")
+		if err == nil {
+			_, err = f.Write(append(data, '\n'))
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", fmt.Errorf("unable to write run log: %v", err)
+		}
+		return path, nil
+	}
+}
+
+// LoadRunLog reads the log of run id from dir, or of the most recent run if id is empty
+func LoadRunLog(dir string, id string) (*RunLog, error) {
+	if id == "" {
+		latest, err := latestRunID(dir)
+		if err != nil {
+			return nil, err
+		}
+		id = latest
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read run log: %v", err)
+	}
+
+	run := &RunLog{}
+	if err := json.Unmarshal(data, run); err != nil {
+		return nil, fmt.Errorf("unable to parse run log: %v", err)
+	}
+	return run, nil
+}
+
+// TrashedItems returns the items moved to trash by the runs logged in dir,
+// mapped to when they were trashed. A missing dir means no runs.
+func TrashedItems(dir string) (map[string]time.Time, error) {
+	trashed := make(map[string]time.Time)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return trashed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read run log directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		run, err := LoadRunLog(dir, strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if run.Permanent || run.Action == ActionArchive {
+			continue
+		}
+		for _, item := range run.Items {
+			trashed[item.ID] = item.RemovedAt
+		}
+	}
+	return trashed, nil
+}
+
+// latestRunID returns the ID of the most recent run logged in dir
+func latestRunID(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("unable to read run log directory: %v", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no cleanup runs logged in '%s'", dir)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		bi, ni := splitRunID(ids[i])
+		bj, nj := splitRunID(ids[j])
+		if bi != bj {
+			return bi < bj
+		}
+		return ni < nj
+	})
+	return ids[len(ids)-1], nil
+}
+
+// splitRunID splits a run ID into its timestamp and the suffix SaveRunLog
+// adds for runs of the same second, 1 when there is none
+func splitRunID(id string) (string, int) {
+	base, suffix, ok := strings.Cut(id, "-")
+	if !ok {
+		return id, 1
+	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil {
+		return id, 1
+	}
+	return base, n
+}
+
+// UndoResult reports what Undo did with each item of a run
+type UndoResult struct {
+	Restored []string
+	// Missing are items that no longer exist, e.g. because the trash was emptied
+	Missing []string
+	// Skipped are items that were not in trash anymore
+	Skipped []string
+	// Failed are items that could not be checked or restored, with the error
+	Failed []string
+}
+
+// Undo restores the items of a run from trash. Items are restored in reverse
+// order, so the parent folders of a date hierarchy are back before their children.
+func (c *CleanupService) Undo(ctx context.Context, run *RunLog) (UndoResult, error) {
+	var result UndoResult
+	if run.Permanent {
+		return result, fmt.Errorf("run %s permanently deleted its items, they cannot be restored", run.ID)
+	}
+	if run.Action == ActionArchive {
+		return result, fmt.Errorf("run %s archived its items, move them back from the archive folder instead", run.ID)
+	}
+
+	for i := len(run.Items) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		item := run.Items[i]
+		file, err := c.driveService.GetFile(ctx, item.ID)
+		if errors.Is(err, driveclient.ErrNotFound) {
+			log.Printf("Permanently deleted, cannot restore: %s", item.Path)
+			result.Missing = append(result.Missing, item.Path)
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: unable to check '%s': %v", item.Path, err)
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", item.Path, err))
+			continue
+		}
+		if !file.Trashed {
+			log.Printf("Not in trash, skipping: %s", item.Path)
+			result.Skipped = append(result.Skipped, item.Path)
+			continue
+		}
+
+		log.Printf("Restoring: %s", item.Path)
+		if err := c.driveService.UntrashFile(ctx, item.ID); err != nil {
+			log.Printf("Warning: failed to restore '%s': %v", item.Path, err)
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", item.Path, err))
+			continue
+		}
+		result.Restored = append(result.Restored, item.Path)
+	}
+
+	return result, nil
+}
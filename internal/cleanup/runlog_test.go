@@ -0,0 +1,179 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestRunLogUndo(t *testing.T) {
+	originalNow := now
+	now = func() time.Time { return time.Date(2025, 6, 10, 4, 0, 0, 0, time.UTC) }
+	defer func() { now = originalNow }()
+
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root": {
+				{Id: "d1", Name: "2025-01-01"},
+				{Id: "d2", Name: "2025-01-02"},
+				{Id: "d3", Name: "2025-01-03"},
+				{Id: "d4", Name: "2025-01-04"},
+			},
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyy-MM-dd",
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+	if _, err := c.Run(context.Background(), "root"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	if _, err := SaveRunLog(dir, c.LastRun()); err != nil {
+		t.Fatalf("SaveRunLog() error = %v", err)
+	}
+
+	// An empty run ID loads the most recent run
+	run, err := LoadRunLog(dir, "")
+	if err != nil {
+		t.Fatalf("LoadRunLog() error = %v", err)
+	}
+	if run.ID != "20250610T040000Z" || len(run.Items) != 3 {
+		t.Fatalf("LoadRunLog() = %s with %d items, want 20250610T040000Z with 3", run.ID, len(run.Items))
+	}
+
+	// d1 was permanently deleted from trash and d2 restored by hand since the run
+	fake.trashed = []string{"d3"}
+	fake.deleted = []string{"d1"}
+
+	result, err := c.Undo(context.Background(), run)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+
+	want := UndoResult{
+		Restored: []string{"2025-01-03"},
+		Missing:  []string{"2025-01-01"},
+		Skipped:  []string{"2025-01-02"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Undo() = %+v, want %+v", result, want)
+	}
+	if len(fake.trashed) != 0 {
+		t.Errorf("trashed after undo = %v, want nothing", fake.trashed)
+	}
+}
+
+// untrashFailingDriveService fails to restore the given item
+type untrashFailingDriveService struct {
+	*fakeDriveService
+	failID string
+}
+
+func (f *untrashFailingDriveService) UntrashFile(ctx context.Context, fileID string) error {
+	if fileID == f.failID {
+		return errors.New("rate limited")
+	}
+	return f.fakeDriveService.UntrashFile(ctx, fileID)
+}
+
+func TestRunLogUndo_Failed(t *testing.T) {
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root": {
+				{Id: "d1", Name: "2025-01-01"},
+				{Id: "d2", Name: "2025-01-02"},
+			},
+		},
+		trashed: []string{"d1", "d2"},
+	}
+	run := &RunLog{
+		ID:     "20250610T040000Z",
+		Action: ActionTrash,
+		Items: []RunLogEntry{
+			{ID: "d1", Path: "2025-01-01"},
+			{ID: "d2", Path: "2025-01-02"},
+		},
+	}
+
+	c := NewCleanupService(&untrashFailingDriveService{fakeDriveService: fake, failID: "d1"}, Options{})
+	result, err := c.Undo(context.Background(), run)
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+
+	want := UndoResult{
+		Restored: []string{"2025-01-02"},
+		Failed:   []string{"2025-01-01: rate limited"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Undo() = %+v, want %+v", result, want)
+	}
+}
+
+func TestSaveRunLog_SameSecond(t *testing.T) {
+	dir := t.TempDir()
+
+	var ids []string
+	for i := 0; i < 11; i++ {
+		run := &RunLog{ID: "20250610T040000Z", Action: ActionTrash}
+		path, err := SaveRunLog(dir, run)
+		if err != nil {
+			t.Fatalf("SaveRunLog() error = %v", err)
+		}
+		if filepath.Base(path) != run.ID+".json" {
+			t.Errorf("SaveRunLog() path = %s, want %s.json", path, run.ID)
+		}
+		ids = append(ids, run.ID)
+	}
+
+	if ids[0] != "20250610T040000Z" || ids[1] != "20250610T040000Z-2" || ids[10] != "20250610T040000Z-11" {
+		t.Errorf("run IDs = %v, want 20250610T040000Z, then -2 to -11", ids)
+	}
+
+	// The latest run is the last one saved, not the last in string order
+	run, err := LoadRunLog(dir, "")
+	if err != nil {
+		t.Fatalf("LoadRunLog() error = %v", err)
+	}
+	if run.ID != "20250610T040000Z-11" {
+		t.Errorf("LoadRunLog() = %s, want 20250610T040000Z-11", run.ID)
+	}
+}
+
+func TestTrashedItems(t *testing.T) {
+	dir := t.TempDir()
+	removedAt := time.Date(2025, 6, 10, 4, 0, 0, 0, time.UTC)
+	runs := []*RunLog{
+		{ID: "20250610T040000Z", Action: ActionTrash, Items: []RunLogEntry{{ID: "d1", RemovedAt: removedAt}}},
+		{ID: "20250611T040000Z", Permanent: true, Items: []RunLogEntry{{ID: "d2", RemovedAt: removedAt}}},
+		{ID: "20250612T040000Z", Action: ActionArchive, Items: []RunLogEntry{{ID: "d3", RemovedAt: removedAt}}},
+	}
+	for _, run := range runs {
+		if _, err := SaveRunLog(dir, run); err != nil {
+			t.Fatalf("SaveRunLog() error = %v", err)
+		}
+	}
+
+	trashed, err := TrashedItems(dir)
+	if err != nil {
+		t.Fatalf("TrashedItems() error = %v", err)
+	}
+	want := map[string]time.Time{"d1": removedAt}
+	if !reflect.DeepEqual(trashed, want) {
+		t.Errorf("TrashedItems() = %v, want %v", trashed, want)
+	}
+
+	// No runs logged yet
+	trashed, err = TrashedItems(filepath.Join(dir, "missing"))
+	if err != nil || len(trashed) != 0 {
+		t.Errorf("TrashedItems() on missing dir = %v, %v, want empty", trashed, err)
+	}
+}
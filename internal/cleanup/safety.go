@@ -0,0 +1,143 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Markers that protect an item from cleanup
+const (
+	// PinnedProperty is the appProperty that protects a folder or file when set to "true"
+	PinnedProperty = "gdu.pinned"
+	// KeepMarker is the name of a file that protects the folder containing it and everything below
+	KeepMarker = ".keep"
+)
+
+// isPinned reports whether an item carries the gdu.pinned=true appProperty
+func isPinned(file *drive.File) bool {
+	return file.AppProperties[PinnedProperty] == "true"
+}
+
+// hasKeepMarker reports whether a folder listing contains the .keep marker file
+func hasKeepMarker(files []*drive.File) bool {
+	for _, file := range files {
+		if file.Name == KeepMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// markerProtection returns why a folder itself is marked as protected, pinned
+// or holding a .keep marker, or "" if it is not. It costs one listing.
+func (c *CleanupService) markerProtection(ctx context.Context, folder *drive.File) (string, error) {
+	if isPinned(folder) {
+		return PinnedProperty, nil
+	}
+
+	files, err := c.driveService.ListFiles(ctx, folder.Id)
+	if err != nil {
+		return "", err
+	}
+	if hasKeepMarker(files) {
+		return "contains " + KeepMarker, nil
+	}
+	return "", nil
+}
+
+// folderProtection returns why a folder must not be removed, or "" if it may
+// be. Removing a folder removes everything below it, so a pinned item or a
+// .keep marker anywhere in its subtree protects it too. It costs two listings
+// per subfolder.
+func (c *CleanupService) folderProtection(ctx context.Context, folder *drive.File) (string, error) {
+	if isPinned(folder) {
+		return PinnedProperty, nil
+	}
+	return c.subtreeProtection(ctx, folder, "")
+}
+
+// subtreeProtection looks for protected items below folder; path is the
+// folder's path relative to the one being checked, for the reason
+func (c *CleanupService) subtreeProtection(ctx context.Context, folder *drive.File, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	files, err := c.driveService.ListFiles(ctx, folder.Id)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if file.Name == KeepMarker {
+			return "contains " + joinPath(path, KeepMarker), nil
+		}
+		if isPinned(file) {
+			return "contains pinned " + joinPath(path, file.Name), nil
+		}
+	}
+
+	subfolders, err := c.driveService.ListFolders(ctx, folder.Id)
+	if err != nil {
+		return "", err
+	}
+	for _, sub := range subfolders {
+		subPath := joinPath(path, sub.Name)
+		if isPinned(sub) {
+			return "contains pinned " + subPath, nil
+		}
+		reason, err := c.subtreeProtection(ctx, sub, subPath)
+		if err != nil || reason != "" {
+			return reason, err
+		}
+	}
+	return "", nil
+}
+
+// protection returns why an item selected by the retention policy must not be
+// removed, or "" if it may be
+func (c *CleanupService) protection(ctx context.Context, item FolderWithDate) (string, error) {
+	if reason := c.tooRecent(item.Date, item.File.ModifiedTime); reason != "" {
+		return reason, nil
+	}
+
+	// Files are covered by the .keep marker of their folder, checked during traversal
+	if c.target == TargetFiles {
+		if isPinned(item.File) {
+			return PinnedProperty, nil
+		}
+		return "", nil
+	}
+	return c.folderProtection(ctx, item.File)
+}
+
+// tooRecent returns why an item is younger than the minimum age, or "" if it
+// is old enough. Both the date parsed from the name and the Drive modification
+// time must be older than the minimum age, so a mistyped pattern that parses
+// wrong dates cannot remove fresh backups.
+func (c *CleanupService) tooRecent(date time.Time, modifiedTime string) string {
+	if c.minAge <= 0 {
+		return ""
+	}
+
+	cutoff := now().Add(-c.minAge)
+	if !date.IsZero() && date.After(cutoff) {
+		return fmt.Sprintf("dated within min age %s", c.minAge)
+	}
+	if modified, err := time.Parse(time.RFC3339, modifiedTime); err == nil && modified.After(cutoff) {
+		return fmt.Sprintf("modified within min age %s", c.minAge)
+	}
+	return ""
+}
+
+// CheckMaxDelete fails if a plan removes more items than maxDelete, unless
+// maxDelete is 0. Apply checks it too, but callers asking for confirmation
+// should check it first.
+func CheckMaxDelete(plan *Plan, maxDelete int) error {
+	if maxDelete > 0 && len(plan.Items) > maxDelete {
+		return fmt.Errorf("plan removes %d items, more than the limit of %d; nothing was removed", len(plan.Items), maxDelete)
+	}
+	return nil
+}
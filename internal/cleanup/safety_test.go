@@ -0,0 +1,157 @@
+package cleanup
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestPlanSafety(t *testing.T) {
+	originalNow := now
+	now = func() time.Time { return time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = originalNow }()
+
+	newFake := func() *fakeDriveService {
+		return &fakeDriveService{
+			folders: map[string][]*drive.File{
+				"root": {
+					{Id: "svc", Name: "SERVICE"},
+					{Id: "pinned-svc", Name: "PINNED", AppProperties: map[string]string{PinnedProperty: "true"}},
+					{Id: "kept-svc", Name: "KEPT"},
+				},
+				"svc": {
+					{Id: "d0608", Name: "2025-06-08"},
+					{Id: "d0609", Name: "2025-06-09"},
+					{Id: "d0101", Name: "2025-01-01", ModifiedTime: "2025-06-09T12:00:00Z"},
+					{Id: "d0102", Name: "2025-01-02", AppProperties: map[string]string{PinnedProperty: "true"}},
+					{Id: "d0103", Name: "2025-01-03"},
+					{Id: "d0104", Name: "2025-01-04"},
+				},
+				"pinned-svc": {{Id: "p0101", Name: "2025-01-01"}, {Id: "p0102", Name: "2025-01-02"}},
+				"kept-svc":   {{Id: "k0101", Name: "2025-01-01"}, {Id: "k0102", Name: "2025-01-02"}},
+			},
+			files: map[string][]*drive.File{
+				"d0103":    {{Id: "d0103-keep", Name: KeepMarker}},
+				"kept-svc": {{Id: "kept-svc-keep", Name: KeepMarker}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantTrashed []string
+		wantErr     bool
+	}{
+		{
+			name:        "pinned and .keep are protected",
+			opts:        Options{DatePattern: "yyyy-MM-dd", Retention: RetentionPolicy{Keep: 1}},
+			wantTrashed: []string{"d0101", "d0104", "d0608"},
+		},
+		{
+			name: "min age protects recent dates and recent modifications",
+			opts: Options{DatePattern: "yyyy-MM-dd", Retention: RetentionPolicy{Keep: 1}, MinAge: 7 * 24 * time.Hour},
+			// d0608 is dated within a week and d0101 was modified within a week
+			wantTrashed: []string{"d0104"},
+		},
+		{
+			name:    "max delete aborts before removing anything",
+			opts:    Options{DatePattern: "yyyy-MM-dd", Retention: RetentionPolicy{Keep: 1}, MaxDelete: 2},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFake()
+			c := NewCleanupService(fake, tt.opts)
+
+			_, err := c.Run(context.Background(), "root")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			sort.Strings(fake.trashed)
+			if !reflect.DeepEqual(fake.trashed, tt.wantTrashed) {
+				t.Errorf("trashed = %v, want %v", fake.trashed, tt.wantTrashed)
+			}
+		})
+	}
+}
+
+func TestNestedProtection(t *testing.T) {
+	folder := func(id string, name string) *drive.File {
+		return &drive.File{Id: id, Name: name}
+	}
+
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root": {
+				folder("d0101", "2025-01-01"),
+				folder("d0102", "2025-01-02"),
+				folder("d0103", "2025-01-03"),
+				folder("d0104", "2025-01-04"),
+				folder("d0105", "2025-01-05"),
+			},
+			// .keep two levels down
+			"d0101":    {folder("d0101-db", "db")},
+			"d0101-db": {folder("d0101-db-old", "old")},
+			"d0102":    {{Id: "d0102-db", Name: "db", AppProperties: map[string]string{PinnedProperty: "true"}}},
+			"d0104":    {folder("d0104-db", "db")},
+		},
+		files: map[string][]*drive.File{
+			"d0101-db-old": {{Id: "keep", Name: KeepMarker}},
+			"d0103":        {{Id: "dump", Name: "dump.sql", AppProperties: map[string]string{PinnedProperty: "true"}}},
+			// An unprotected subtree does not stop cleanup
+			"d0104-db": {{Id: "dump2", Name: "dump.sql"}},
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyy-MM-dd",
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+	if _, err := c.Run(context.Background(), "root"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// d0101 (nested .keep), d0102 (pinned subfolder) and d0103 (pinned file) are protected
+	if want := []string{"d0104"}; !reflect.DeepEqual(fake.trashed, want) {
+		t.Errorf("trashed = %v, want %v", fake.trashed, want)
+	}
+}
+
+func TestNestedProtectionInHierarchy(t *testing.T) {
+	folder := func(id string, name string) *drive.File {
+		return &drive.File{Id: id, Name: name}
+	}
+
+	fake := &fakeDriveService{
+		folders: map[string][]*drive.File{
+			"root":     {folder("y2025", "2025")},
+			"y2025":    {folder("y2025m01", "01")},
+			"y2025m01": {folder("d0105", "05"), folder("d0110", "10"), folder("d0120", "20")},
+			"d0105":    {folder("d0105-db", "db")},
+		},
+		files: map[string][]*drive.File{
+			"d0105-db": {{Id: "keep", Name: KeepMarker}},
+		},
+	}
+
+	c := NewCleanupService(fake, Options{
+		DatePattern: "yyyy/MM/dd",
+		Retention:   RetentionPolicy{Keep: 1},
+	})
+	if _, err := c.Run(context.Background(), "root"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The marker below 2025/01/05 protects that day only, not the whole year
+	if want := []string{"d0110"}; !reflect.DeepEqual(fake.trashed, want) {
+		t.Errorf("trashed = %v, want %v", fake.trashed, want)
+	}
+}
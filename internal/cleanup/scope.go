@@ -0,0 +1,85 @@
+package cleanup
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// scope limits which folders a cleanup examines
+type scope struct {
+	include  []string
+	exclude  []string
+	maxDepth int
+}
+
+// validate checks the include and exclude globs
+func (s scope) validate() error {
+	for _, pattern := range append(append([]string{}, s.include...), s.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid path glob '%s': %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// excluded reports whether p or one of its ancestors matches an exclude glob
+func (s scope) excluded(p string) bool {
+	for _, pattern := range s.exclude {
+		if matchesAncestor(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether the children of the folder at p may be cleaned
+// up: p or one of its ancestors matches an include glob, or there are none
+func (s scope) included(p string) bool {
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, pattern := range s.include {
+		if matchesAncestor(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsToInclude reports whether folders below p may match an include glob,
+// so p must be traversed even though it is not included itself
+func (s scope) leadsToInclude(p string) bool {
+	segments := splitPath(p)
+	for _, pattern := range s.include {
+		patternSegments := strings.Split(pattern, "/")
+		if len(segments) >= len(patternSegments) {
+			continue
+		}
+		prefix := strings.Join(patternSegments[:len(segments)], "/")
+		if ok, _ := path.Match(prefix, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAncestor reports whether pattern matches p or one of its ancestors.
+// Each "/"-separated segment of the pattern matches one folder level.
+func matchesAncestor(pattern string, p string) bool {
+	segments := splitPath(p)
+	patternSegments := strings.Split(pattern, "/")
+	if len(segments) < len(patternSegments) {
+		return false
+	}
+	ok, _ := path.Match(pattern, strings.Join(segments[:len(patternSegments)], "/"))
+	return ok
+}
+
+// splitPath splits a slash-separated Drive path into folder names, the root being no names
+func splitPath(p string) []string {
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,128 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+)
+
+func TestScope(t *testing.T) {
+	s := scope{include: []string{"SVC-*/db"}, exclude: []string{"SVC-b"}}
+
+	tests := []struct {
+		path         string
+		wantIncluded bool
+		wantLeads    bool
+		wantExcluded bool
+	}{
+		{path: "", wantLeads: true},
+		{path: "SVC-a", wantLeads: true},
+		{path: "SVC-a/db", wantIncluded: true},
+		{path: "SVC-a/db/2025", wantIncluded: true},
+		{path: "SVC-a/web"},
+		{path: "OTHER"},
+		{path: "SVC-b", wantLeads: true, wantExcluded: true},
+		{path: "SVC-b/db", wantIncluded: true, wantExcluded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := s.included(tt.path); got != tt.wantIncluded {
+				t.Errorf("included(%q) = %v, want %v", tt.path, got, tt.wantIncluded)
+			}
+			if got := s.leadsToInclude(tt.path); got != tt.wantLeads {
+				t.Errorf("leadsToInclude(%q) = %v, want %v", tt.path, got, tt.wantLeads)
+			}
+			if got := s.excluded(tt.path); got != tt.wantExcluded {
+				t.Errorf("excluded(%q) = %v, want %v", tt.path, got, tt.wantExcluded)
+			}
+		})
+	}
+}
+
+// failingDriveService fails to list the children of one folder
+type failingDriveService struct {
+	*fakeDriveService
+	failID string
+}
+
+func (f *failingDriveService) ListFolders(ctx context.Context, parentID string) ([]*drive.File, error) {
+	if parentID == f.failID {
+		return nil, errors.New("backend error")
+	}
+	return f.fakeDriveService.ListFolders(ctx, parentID)
+}
+
+func TestRunTraversal(t *testing.T) {
+	folder := func(id string, name string) *drive.File {
+		return &drive.File{Id: id, Name: name}
+	}
+
+	newFake := func() *fakeDriveService {
+		return &fakeDriveService{
+			folders: map[string][]*drive.File{
+				"root":  {folder("a", "SVC-a"), folder("b", "SVC-b"), folder("c", "OTHER")},
+				"a":     {folder("a-db", "db"), folder("a-web", "web")},
+				"b":     {folder("b-db", "db")},
+				"c":     {folder("c1", "2025-01-01"), folder("c2", "2025-01-02")},
+				"a-db":  {folder("a-db1", "2025-01-01"), folder("a-db2", "2025-01-02")},
+				"a-web": {folder("a-web1", "2025-01-01"), folder("a-web2", "2025-01-02")},
+				"b-db":  {folder("b-db1", "2025-01-01"), folder("b-db2", "2025-01-02")},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		opts        Options
+		failID      string
+		wantTrashed []string
+		wantErr     bool
+	}{
+		{
+			name:        "parallel traversal",
+			opts:        Options{Concurrency: 3},
+			wantTrashed: []string{"a-db1", "a-web1", "b-db1", "c1"},
+		},
+		{
+			name:        "max depth",
+			opts:        Options{MaxDepth: 1},
+			wantTrashed: []string{"c1"},
+		},
+		{
+			name:        "include and exclude globs",
+			opts:        Options{PathInclude: []string{"SVC-*/db"}, PathExclude: []string{"SVC-b"}},
+			wantTrashed: []string{"a-db1"},
+		},
+		{
+			name:        "a failing branch does not stop the others",
+			opts:        Options{Concurrency: 2},
+			failID:      "a",
+			wantTrashed: []string{"b-db1", "c1"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFake()
+			tt.opts.DatePattern = "yyyy-MM-dd"
+			tt.opts.Retention = RetentionPolicy{Keep: 1}
+			c := NewCleanupService(&failingDriveService{fakeDriveService: fake, failID: tt.failID}, tt.opts)
+
+			_, err := c.Run(context.Background(), "root")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			sort.Strings(fake.trashed)
+			if !reflect.DeepEqual(fake.trashed, tt.wantTrashed) {
+				t.Errorf("trashed = %v, want %v", fake.trashed, tt.wantTrashed)
+			}
+		})
+	}
+}
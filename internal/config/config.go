@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // Config holds the configuration for the application
 type Config struct {
 	ClientSecret    string
@@ -12,11 +14,94 @@ type Config struct {
 	DeleteOnSuccess bool
 	DeleteOnDone    bool
 
+	// ParseRules is a JSON file of filename rules for smart organize and file
+	// cleanup, tried before the built-in [service]_backup_[date] convention
+	ParseRules string
+	// Timezone is the IANA zone the UTC times in filenames are converted to
+	// for date folders and cleanup, UTC when empty
+	Timezone string
+	// ServiceCase is how service folder names are written: "upper-snake",
+	// "lower-snake", "kebab" or "preserve"
+	ServiceCase string
+	// ServiceAliases maps service names to a canonical folder name, e.g. kc=KEYCLOAK
+	ServiceAliases map[string]string
+	// OrganizeLayout is the folder hierarchy template of smart organize, e.g.
+	// {env}/{service}/{yyyy}/{MM}/{dd}; OrganizeDefaults fills fields a filename lacks
+	OrganizeLayout   string
+	OrganizeDefaults map[string]string
+	// OrganizeFallback is what smart organize does with files no parse rule
+	// matches: "none", "unsorted", "mtime" or "reject" (default: the rules file's)
+	OrganizeFallback string
+
+	// Timeouts (zero means no limit)
+	Timeout     time.Duration
+	FileTimeout time.Duration
+
+	// TokenKeyFile holds the base64 key the token file is encrypted with; without
+	// it the GDU_TOKEN_KEY and GDU_TOKEN_PASSPHRASE variables are used, if set
+	TokenKeyFile string
+	// TokenHelper is a command that prints the token on "get" and receives
+	// refreshed tokens on "store", used instead of TokenPath
+	TokenHelper string
+
 	// Token generation mode
 	TokenGen bool
+	// DeviceFlow authorizes with a code entered on another device instead of a local browser
+	DeviceFlow bool
+
+	// ServiceAccount is a service account JSON key used instead of the OAuth
+	// token, Impersonate the Workspace user it acts as (domain-wide delegation)
+	ServiceAccount string
+	Impersonate    string
+
+	// Yes skips confirmation prompts for destructive operations
+	Yes bool
 
 	// Cleanup flags
 	Cleanup      bool
 	Keep         int
 	MatchPattern string
+	MatchRegex   string
+	OlderThan    string
+	// MaxSize is a byte budget per group of date folders, e.g. 200GB
+	MaxSize string
+	// Permanent deletes expired items instead of moving them to trash
+	Permanent bool
+	// Action is "trash" or "archive", which moves expired items below ArchiveFolderID
+	Action          string
+	ArchiveFolderID string
+	// CleanupTarget is "folders" (date-named folders) or "files" (date-stamped files)
+	CleanupTarget string
+	// DateSource is "name" (parse --match from names), "created" or "modified" (Drive timestamps)
+	DateSource string
+
+	// Safety limits (cleanup mode): MaxDelete aborts runs removing more items
+	// (0 means no limit), MinAge protects recent items, e.g. 7d
+	MaxDelete int
+	MinAge    string
+
+	// Traversal (cleanup mode): Concurrency bounds parallel Drive requests,
+	// MaxDepth limits the folder levels examined (0 means no limit) and the
+	// path globs scope which subtrees are examined
+	Concurrency int
+	MaxDepth    int
+	PathInclude []string
+	PathExclude []string
+
+	// RunLogDir is where each cleanup run logs the items it removed, for "cleanup undo"
+	// and "trash purge"
+	RunLogDir string
+	// PurgeAll makes "trash purge" consider the whole trash, not only what cleanup runs trashed
+	PurgeAll bool
+	// RunID selects the run "cleanup undo" restores, the most recent when empty
+	RunID string
+
+	// PlanOut is the file "cleanup plan" writes the reviewable plan to
+	PlanOut string
+
+	// Grandfather-father-son retention (cleanup mode)
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+	KeepYearly  int
 }
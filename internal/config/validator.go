@@ -3,31 +3,47 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"time"
 )
 
 const (
 	defaultConfigDir       = "/etc/google-drive-uploader"
 	defaultTokenFile       = "token.json"
 	defaultCredentialsFile = "client-secret.json"
+	defaultRunLogDir       = "runs"
+	defaultParseRulesFile  = "parse-rules.json"
 )
 
+// TokenJSONEnv holds the token JSON, raw or base64, used instead of the token file
+const TokenJSONEnv = "GDU_TOKEN_JSON"
+
 var (
 	DefaultTokenFilePath        = filepath.Join(defaultConfigDir, defaultTokenFile)
 	DefaultCredentialsFilesPath = filepath.Join(defaultConfigDir, defaultCredentialsFile)
+	DefaultRunLogDir            = filepath.Join(defaultConfigDir, defaultRunLogDir)
+	DefaultParseRulesPath       = filepath.Join(defaultConfigDir, defaultParseRulesFile)
 )
 
 // Validate checks the configuration for errors and sets defaults
 func (c *Config) Validate(args []string) error {
 	// Handle token generation mode validation
 	if c.TokenGen {
+		if c.ServiceAccount != "" {
+			return fmt.Errorf("--token-gen is not needed with --service-account")
+		}
 		if _, err := os.Stat(c.ClientSecret); err != nil {
 			return err
 		}
-		return nil
+		return c.validateTokenKey()
 	}
 
 	// Normal mode validation
+	if c.Timeout < 0 || c.FileTimeout < 0 {
+		return fmt.Errorf("--timeout and --file-timeout must not be negative")
+	}
+
 	if c.RootFolderID == "" {
 		return fmt.Errorf("--root-folder-id is required")
 	}
@@ -37,21 +53,219 @@ func (c *Config) Validate(args []string) error {
 	}
 
 	// Only require client-secret if we don't have a token and we are NOT in token-gen mode (already checked above)
-	if !c.TokenGen {
-		if _, err := os.Stat(c.TokenPath); err != nil {
-			return err
-		}
+	if err := c.validateCredentials(); err != nil {
+		return err
+	}
+
+	if err := c.ValidateParseRules(); err != nil {
+		return err
 	}
 
 	// Validate cleanup-specific flags
 	if c.Cleanup {
-		if c.Keep < 1 {
+		if c.KeepDaily < 0 || c.KeepWeekly < 0 || c.KeepMonthly < 0 || c.KeepYearly < 0 {
+			return fmt.Errorf("--keep-daily, --keep-weekly, --keep-monthly and --keep-yearly must not be negative")
+		}
+		// --keep 0 is only allowed when a grandfather-father-son rule keeps something
+		usesGFS := c.KeepDaily+c.KeepWeekly+c.KeepMonthly+c.KeepYearly > 0
+		if c.Keep < 0 || (c.Keep < 1 && !usesGFS) {
 			return fmt.Errorf("--keep must be at least 1")
 		}
-		if c.MatchPattern == "" {
+		if c.DateSource != "" && c.DateSource != "name" && c.DateSource != "created" && c.DateSource != "modified" {
+			return fmt.Errorf("--date-source must be 'name', 'created' or 'modified'")
+		}
+		// With a Drive timestamp, --match is only a name filter, but something
+		// must narrow the scope or every child of the root forms one group
+		usesNames := c.DateSource == "" || c.DateSource == "name"
+		if c.MatchPattern == "" && usesNames {
 			return fmt.Errorf("--match pattern is required for cleanup mode")
 		}
+		if !usesNames && c.MatchPattern == "" && c.MatchRegex == "" && len(c.PathInclude) == 0 {
+			return fmt.Errorf("--date-source %s requires --match, --match-regex or --path-include", c.DateSource)
+		}
+		if c.CleanupTarget != "" && c.CleanupTarget != "folders" && c.CleanupTarget != "files" {
+			return fmt.Errorf("--cleanup-target must be 'folders' or 'files'")
+		}
+		if c.OlderThan != "" {
+			if _, err := ParseAge(c.OlderThan); err != nil {
+				return fmt.Errorf("--older-than: %w", err)
+			}
+		}
+		if c.MaxSize != "" {
+			if _, err := ParseSize(c.MaxSize); err != nil {
+				return fmt.Errorf("--max-size: %w", err)
+			}
+		}
+		if c.Action != "" && c.Action != "trash" && c.Action != "archive" {
+			return fmt.Errorf("--action must be 'trash' or 'archive'")
+		}
+		if c.Action == "archive" {
+			if c.ArchiveFolderID == "" {
+				return fmt.Errorf("--archive-folder-id is required with --action archive")
+			}
+			if c.Permanent {
+				return fmt.Errorf("--permanent cannot be used with --action archive")
+			}
+		}
+		if err := c.validateSafetyLimits(); err != nil {
+			return err
+		}
+		if err := c.validateTraversal(); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// ValidateCleanupPlan checks the configuration of the cleanup plan command
+func (c *Config) ValidateCleanupPlan() error {
+	if c.PlanOut == "" {
+		return fmt.Errorf("--out is required")
+	}
+
+	c.Cleanup = true
+	return c.Validate(nil)
+}
+
+// ValidateCleanupApply checks the configuration of the cleanup apply command
+func (c *Config) ValidateCleanupApply(planPath string) error {
+	if err := c.validateCredentials(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(planPath); err != nil {
+		return err
+	}
+
+	return c.validateSafetyLimits()
+}
+
+// validateTraversal checks --concurrency, --max-depth and the path globs
+func (c *Config) validateTraversal() error {
+	if c.Concurrency < 0 || c.MaxDepth < 0 {
+		return fmt.Errorf("--concurrency and --max-depth must not be negative")
+	}
+	for _, pattern := range append(append([]string{}, c.PathInclude...), c.PathExclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid path glob '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateSafetyLimits checks --max-delete and --min-age
+func (c *Config) validateSafetyLimits() error {
+	if c.MaxDelete < 0 {
+		return fmt.Errorf("--max-delete must not be negative")
+	}
+	if c.MinAge != "" {
+		if _, err := ParseAge(c.MinAge); err != nil {
+			return fmt.Errorf("--min-age: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateCleanupUndo checks the configuration of the cleanup undo command
+func (c *Config) ValidateCleanupUndo() error {
+	if err := c.validateCredentials(); err != nil {
+		return err
+	}
+
+	if c.RunLogDir == "" {
+		return fmt.Errorf("--run-log-dir is required")
+	}
+
+	return nil
+}
+
+// ValidateTrashPurge checks the configuration of the trash purge command
+func (c *Config) ValidateTrashPurge() error {
+	if err := c.validateCredentials(); err != nil {
+		return err
+	}
+
+	if c.OlderThan != "" {
+		if _, err := ParseAge(c.OlderThan); err != nil {
+			return fmt.Errorf("--older-than: %w", err)
+		}
+	}
+
+	if c.RunLogDir == "" && !c.PurgeAll {
+		return fmt.Errorf("--run-log-dir is required to find the items cleanup trashed, or use --all to purge the whole trash")
+	}
+
+	return nil
+}
+
+// ValidateParseRules checks that an explicit --parse-rules file exists and
+// that --timezone, --organize-fallback and --service-case are valid. The default rules file is optional and only used if present.
+func (c *Config) ValidateParseRules() error {
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("--timezone: %w", err)
+	}
+	if c.OrganizeFallback != "" && c.OrganizeFallback != "none" && c.OrganizeFallback != "unsorted" && c.OrganizeFallback != "mtime" && c.OrganizeFallback != "reject" {
+		return fmt.Errorf("--organize-fallback must be 'none', 'unsorted', 'mtime' or 'reject'")
+	}
+	if c.ServiceCase != "" && c.ServiceCase != "upper-snake" && c.ServiceCase != "lower-snake" && c.ServiceCase != "kebab" && c.ServiceCase != "preserve" {
+		return fmt.Errorf("--service-case must be 'upper-snake', 'lower-snake', 'kebab' or 'preserve'")
+	}
+
+	if c.ParseRules == "" || c.ParseRules == DefaultParseRulesPath {
+		return nil
+	}
+	if _, err := os.Stat(c.ParseRules); err != nil {
+		return fmt.Errorf("--parse-rules: %w", err)
+	}
+	return nil
+}
+
+// validateCredentials checks that an existing token, or a service account key, can be used to authenticate
+func (c *Config) validateCredentials() error {
+	if c.ServiceAccount != "" {
+		if _, err := os.Stat(c.ServiceAccount); err != nil {
+			return fmt.Errorf("--service-account: %w", err)
+		}
+		return nil
+	}
+
+	if c.Impersonate != "" {
+		return fmt.Errorf("--impersonate requires --service-account")
+	}
+	if err := c.validateTokenSource(); err != nil {
+		return err
+	}
+	return c.validateTokenKey()
+}
+
+// ValidateTokenEncrypt checks the configuration of the token encrypt command
+func (c *Config) ValidateTokenEncrypt() error {
+	if err := c.validateTokenSource(); err != nil {
+		return err
+	}
+	return c.validateTokenKey()
+}
+
+// validateTokenSource checks that the token file exists, unless the token
+// comes from --token-helper or GDU_TOKEN_JSON
+func (c *Config) validateTokenSource() error {
+	if c.TokenHelper != "" || os.Getenv(TokenJSONEnv) != "" {
+		return nil
+	}
+	if _, err := os.Stat(c.TokenPath); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateTokenKey checks that an explicit --token-key-file exists
+func (c *Config) validateTokenKey() error {
+	if c.TokenKeyFile == "" {
+		return nil
+	}
+	if _, err := os.Stat(c.TokenKeyFile); err != nil {
+		return fmt.Errorf("--token-key-file: %w", err)
+	}
+	return nil
+}
@@ -43,6 +43,42 @@ func TestConfig_Validate_Upload(t *testing.T) {
 			args:    []string{},
 			wantErr: false,
 		},
+		{
+			name: "Missing parse rules file",
+			config: Config{
+				RootFolderID:  "folder123",
+				ClientSecret:  apiKeyPath,
+				TokenPath:     tokenPath,
+				SmartOrganize: true,
+				ParseRules:    filepath.Join(tempDir, "missing-rules.json"),
+			},
+			args:    []string{"file.txt"},
+			wantErr: true,
+		},
+		{
+			name: "Invalid organize fallback",
+			config: Config{
+				RootFolderID:     "folder123",
+				ClientSecret:     apiKeyPath,
+				TokenPath:        tokenPath,
+				SmartOrganize:    true,
+				OrganizeFallback: "guess",
+			},
+			args:    []string{"file.txt"},
+			wantErr: true,
+		},
+		{
+			name: "Default parse rules file is optional",
+			config: Config{
+				RootFolderID:  "folder123",
+				ClientSecret:  apiKeyPath,
+				TokenPath:     tokenPath,
+				SmartOrganize: true,
+				ParseRules:    DefaultParseRulesPath,
+			},
+			args:    []string{"file.txt"},
+			wantErr: false,
+		},
 		{
 			name: "Missing root folder ID",
 			config: Config{
@@ -88,6 +124,152 @@ func TestConfig_Validate_Upload(t *testing.T) {
 			args:    []string{},
 			wantErr: true,
 		},
+		{
+			name: "Valid GFS cleanup config without --keep",
+			config: Config{
+				RootFolderID: "folder123",
+				ClientSecret: apiKeyPath,
+				TokenPath:    tokenPath,
+				Cleanup:      true,
+				Keep:         0,
+				KeepDaily:    7,
+				KeepMonthly:  12,
+				MatchPattern: "yyyy-MM-dd",
+			},
+			args:    []string{},
+			wantErr: false,
+		},
+		{
+			name: "Cleanup by created time without --match or --path-include",
+			config: Config{
+				RootFolderID: "folder123",
+				ClientSecret: apiKeyPath,
+				TokenPath:    tokenPath,
+				Cleanup:      true,
+				Keep:         3,
+				DateSource:   "created",
+			},
+			args:    []string{},
+			wantErr: true,
+		},
+		{
+			name: "Valid cleanup by created time with --path-include",
+			config: Config{
+				RootFolderID: "folder123",
+				ClientSecret: apiKeyPath,
+				TokenPath:    tokenPath,
+				Cleanup:      true,
+				Keep:         3,
+				DateSource:   "created",
+				PathInclude:  []string{"backups/*"},
+			},
+			args:    []string{},
+			wantErr: false,
+		},
+		{
+			name: "Valid cleanup by modified time with --match-regex",
+			config: Config{
+				RootFolderID: "folder123",
+				ClientSecret: apiKeyPath,
+				TokenPath:    tokenPath,
+				Cleanup:      true,
+				Keep:         3,
+				DateSource:   "modified",
+				MatchRegex:   `^nightly-`,
+			},
+			args:    []string{},
+			wantErr: false,
+		},
+		{
+			name: "Invalid cleanup date source",
+			config: Config{
+				RootFolderID: "folder123",
+				ClientSecret: apiKeyPath,
+				TokenPath:    tokenPath,
+				Cleanup:      true,
+				Keep:         3,
+				MatchPattern: "yyyy-MM-dd",
+				DateSource:   "viewed",
+			},
+			args:    []string{},
+			wantErr: true,
+		},
+		{
+			name: "Archive cleanup without archive folder",
+			config: Config{
+				RootFolderID: "folder123",
+				ClientSecret: apiKeyPath,
+				TokenPath:    tokenPath,
+				Cleanup:      true,
+				Keep:         3,
+				MatchPattern: "yyyy-MM-dd",
+				Action:       "archive",
+			},
+			args:    []string{},
+			wantErr: true,
+		},
+		{
+			name: "Service account without token",
+			config: Config{
+				RootFolderID:   "folder123",
+				TokenPath:      filepath.Join(tempDir, "missing-token.json"),
+				ServiceAccount: apiKeyPath,
+				Impersonate:    "backup@example.com",
+			},
+			args:    []string{"file.txt"},
+			wantErr: false,
+		},
+		{
+			name: "Missing service account key",
+			config: Config{
+				RootFolderID:   "folder123",
+				TokenPath:      tokenPath,
+				ServiceAccount: filepath.Join(tempDir, "missing-key.json"),
+			},
+			args:    []string{"file.txt"},
+			wantErr: true,
+		},
+		{
+			name: "Impersonate without service account",
+			config: Config{
+				RootFolderID: "folder123",
+				TokenPath:    tokenPath,
+				Impersonate:  "backup@example.com",
+			},
+			args:    []string{"file.txt"},
+			wantErr: true,
+		},
+		{
+			name: "Token helper without token file",
+			config: Config{
+				RootFolderID: "folder123",
+				TokenPath:    filepath.Join(tempDir, "missing-token.json"),
+				TokenHelper:  "vault-token-helper",
+			},
+			args:    []string{"file.txt"},
+			wantErr: false,
+		},
+		{
+			name: "Missing token key file",
+			config: Config{
+				RootFolderID: "folder123",
+				TokenPath:    tokenPath,
+				TokenKeyFile: filepath.Join(tempDir, "missing.key"),
+			},
+			args:    []string{"file.txt"},
+			wantErr: true,
+		},
+		{
+			name: "Negative file timeout",
+			config: Config{
+				RootFolderID: "folder123",
+				ClientSecret: apiKeyPath,
+				TokenPath:    tokenPath,
+				FileTimeout:  -1,
+			},
+			args:    []string{"file.txt"},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -97,6 +279,17 @@ func TestConfig_Validate_Upload(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("Token from environment without token file", func(t *testing.T) {
+		t.Setenv(TokenJSONEnv, "{}")
+		config := Config{
+			RootFolderID: "folder123",
+			TokenPath:    filepath.Join(tempDir, "missing-token.json"),
+		}
+		if err := config.Validate([]string{"file.txt"}); err != nil {
+			t.Errorf("Config.Validate() error = %v, want nil", err)
+		}
+	})
 }
 
 func TestConfig_Validate_TokenGen(t *testing.T) {
@@ -130,4 +323,40 @@ func TestConfig_Validate_TokenGen(t *testing.T) {
 			t.Error("Config.Validate() error = nil, want error")
 		}
 	})
+
+	t.Run("Token-gen with service account", func(t *testing.T) {
+		config := Config{
+			TokenGen:       true,
+			ClientSecret:   apiKeyPath,
+			ServiceAccount: apiKeyPath,
+		}
+		if err := config.Validate([]string{}); err == nil {
+			t.Error("Config.Validate() error = nil, want error")
+		}
+	})
+}
+
+func TestConfig_ValidateTrashPurge(t *testing.T) {
+	tempDir := t.TempDir()
+	tokenPath := filepath.Join(tempDir, "token.json")
+	os.WriteFile(tokenPath, []byte("{}"), 0600)
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "Logged items", config: Config{TokenPath: tokenPath, RunLogDir: tempDir}},
+		{name: "Whole trash", config: Config{TokenPath: tokenPath, PurgeAll: true}},
+		{name: "No run logs without --all", config: Config{TokenPath: tokenPath}, wantErr: true},
+		{name: "Invalid older than", config: Config{TokenPath: tokenPath, RunLogDir: tempDir, OlderThan: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.ValidateTrashPurge(); (err != nil) != tt.wantErr {
+				t.Errorf("Config.ValidateTrashPurge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
 }
@@ -0,0 +1,55 @@
+package driveclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Retry policy for requests rejected by the Drive API rate limits
+const (
+	maxRateLimitRetries = 5
+	initialBackoff      = time.Second
+)
+
+// doList runs a list call, retrying with exponential backoff while Drive reports a rate limit
+func doList(ctx context.Context, call *drive.FilesListCall) (*drive.FileList, error) {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		r, err := call.Do()
+		if err == nil || !isRateLimited(err) || attempt == maxRateLimitRetries {
+			return r, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// isRateLimited reports whether Drive rejected a request because of a rate limit
+func isRateLimited(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	if apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "rateLimitExceeded" || item.Reason == "userRateLimitExceeded" {
+			return true
+		}
+	}
+	return false
+}
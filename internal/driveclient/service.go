@@ -2,15 +2,20 @@ package driveclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
+// ErrNotFound is returned by GetFile when the file does not exist, e.g. because it was permanently deleted
+var ErrNotFound = errors.New("file not found")
+
 // Service defines the interface for interacting with Google Drive
 type Service interface {
 	UploadFile(ctx context.Context, file io.Reader, filename string, parentID string) (*drive.File, error)
@@ -42,7 +47,7 @@ func (s *DriveService) UploadFile(ctx context.Context, file io.Reader, filename
 
 	// We can add Progress reporting if needed by wrapping the reader,
 	// but for now we stick to the basic resumable upload.
-	res, err := s.srv.Files.Create(f).Media(file).Do()
+	res, err := s.srv.Files.Create(f).Media(file).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("could not upload file: %v", err)
 	}
@@ -65,7 +70,7 @@ func (s *DriveService) FindOrCreateFolder(ctx context.Context, name string, pare
 	escapedName := strings.ReplaceAll(name, "'", "\\'")
 	q = fmt.Sprintf("mimeType = 'application/vnd.google-apps.folder' and name = '%s' and '%s' in parents and trashed = false", escapedName, parentID)
 
-	r, err := s.srv.Files.List().PageSize(1).Q(q).Fields("nextPageToken, files(id, name)").Do()
+	r, err := s.srv.Files.List().PageSize(1).Q(q).Fields("nextPageToken, files(id, name)").Context(ctx).Do()
 	if err != nil {
 		return "", fmt.Errorf("unable to retrieve files: %v", err)
 	}
@@ -81,7 +86,7 @@ func (s *DriveService) FindOrCreateFolder(ctx context.Context, name string, pare
 		Parents:  []string{parentID},
 	}
 
-	res, err := s.srv.Files.Create(f).Do()
+	res, err := s.srv.Files.Create(f).Context(ctx).Do()
 	if err != nil {
 		return "", fmt.Errorf("could not create folder: %v", err)
 	}
@@ -100,13 +105,14 @@ func (s *DriveService) ListFolders(ctx context.Context, parentID string) ([]*dri
 		call := s.srv.Files.List().
 			PageSize(100).
 			Q(q).
-			Fields("nextPageToken, files(id, name)")
+			Fields("nextPageToken, files(id, name, createdTime, modifiedTime, appProperties)").
+			Context(ctx)
 
 		if pageToken != "" {
 			call = call.PageToken(pageToken)
 		}
 
-		r, err := call.Do()
+		r, err := doList(ctx, call)
 		if err != nil {
 			return nil, fmt.Errorf("unable to retrieve folders: %v", err)
 		}
@@ -122,11 +128,107 @@ func (s *DriveService) ListFolders(ctx context.Context, parentID string) ([]*dri
 	return allFolders, nil
 }
 
+// ListFiles lists all non-folder files within a parent folder
+func (s *DriveService) ListFiles(ctx context.Context, parentID string) ([]*drive.File, error) {
+	q := fmt.Sprintf("mimeType != 'application/vnd.google-apps.folder' and '%s' in parents and trashed = false", parentID)
+
+	var allFiles []*drive.File
+	pageToken := ""
+
+	for {
+		call := s.srv.Files.List().
+			PageSize(100).
+			Q(q).
+			Fields("nextPageToken, files(id, name, size, createdTime, modifiedTime, appProperties)").
+			Context(ctx)
+
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		r, err := doList(ctx, call)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve files: %v", err)
+		}
+
+		allFiles = append(allFiles, r.Files...)
+
+		pageToken = r.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+
+	return allFiles, nil
+}
+
+// FolderSize returns the total size in bytes of all files below a folder, recursively.
+// Google Docs editor files have no size and count as zero.
+func (s *DriveService) FolderSize(ctx context.Context, folderID string) (int64, error) {
+	q := fmt.Sprintf("'%s' in parents and trashed = false", folderID)
+
+	var total int64
+	pageToken := ""
+
+	for {
+		call := s.srv.Files.List().
+			PageSize(1000).
+			Q(q).
+			Fields("nextPageToken, files(id, mimeType, size)").
+			Context(ctx)
+
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		r, err := doList(ctx, call)
+		if err != nil {
+			return 0, fmt.Errorf("unable to retrieve files: %v", err)
+		}
+
+		for _, f := range r.Files {
+			if f.MimeType != "application/vnd.google-apps.folder" {
+				total += f.Size
+				continue
+			}
+			size, err := s.FolderSize(ctx, f.Id)
+			if err != nil {
+				return 0, err
+			}
+			total += size
+		}
+
+		pageToken = r.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+
+	return total, nil
+}
+
+// GetFile retrieves the metadata of a single file or folder
+func (s *DriveService) GetFile(ctx context.Context, fileID string) (*drive.File, error) {
+	f, err := s.srv.Files.Get(fileID).
+		Fields("id, name, mimeType, parents, modifiedTime, trashed, appProperties").
+		Context(ctx).
+		Do()
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		return nil, fmt.Errorf("could not get file: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get file: %v", err)
+	}
+
+	return f, nil
+}
+
 // TrashFile moves a file or folder to trash
 func (s *DriveService) TrashFile(ctx context.Context, fileID string) error {
 	_, err := s.srv.Files.Update(fileID, &drive.File{
 		Trashed: true,
-	}).Do()
+	}).Context(ctx).Do()
 
 	if err != nil {
 		return fmt.Errorf("could not trash file: %v", err)
@@ -134,3 +236,85 @@ func (s *DriveService) TrashFile(ctx context.Context, fileID string) error {
 
 	return nil
 }
+
+// UntrashFile restores a file or folder from trash
+func (s *DriveService) UntrashFile(ctx context.Context, fileID string) error {
+	// Trashed is false, its zero value, so it must be sent explicitly
+	_, err := s.srv.Files.Update(fileID, &drive.File{
+		Trashed:         false,
+		ForceSendFields: []string{"Trashed"},
+	}).Context(ctx).Do()
+
+	if err != nil {
+		return fmt.Errorf("could not restore file: %v", err)
+	}
+
+	return nil
+}
+
+// MoveFile moves a file or folder into newParentID, removing it from its current parents
+func (s *DriveService) MoveFile(ctx context.Context, fileID string, newParentID string) error {
+	f, err := s.srv.Files.Get(fileID).Fields("parents").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("could not get file parents: %v", err)
+	}
+
+	_, err = s.srv.Files.Update(fileID, &drive.File{}).
+		AddParents(newParentID).
+		RemoveParents(strings.Join(f.Parents, ",")).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("could not move file: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteFile permanently deletes a file or folder, skipping the trash.
+// Deleting a folder also deletes all of its descendants.
+func (s *DriveService) DeleteFile(ctx context.Context, fileID string) error {
+	err := s.srv.Files.Delete(fileID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("could not delete file: %v", err)
+	}
+
+	return nil
+}
+
+// ListTrashed lists all items that were explicitly moved to trash.
+// Items trashed only because their parent folder was trashed are not included.
+func (s *DriveService) ListTrashed(ctx context.Context) ([]*drive.File, error) {
+	var allFiles []*drive.File
+	pageToken := ""
+
+	for {
+		call := s.srv.Files.List().
+			PageSize(100).
+			Q("trashed = true").
+			Fields("nextPageToken, files(id, name, mimeType, explicitlyTrashed, trashedTime, modifiedTime)").
+			Context(ctx)
+
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		r, err := doList(ctx, call)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve trashed files: %v", err)
+		}
+
+		for _, f := range r.Files {
+			if f.ExplicitlyTrashed {
+				allFiles = append(allFiles, f)
+			}
+		}
+
+		pageToken = r.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+
+	return allFiles, nil
+}
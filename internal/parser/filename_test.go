@@ -3,6 +3,7 @@ package parser
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestParseFilename(t *testing.T) {
@@ -16,8 +17,10 @@ func TestParseFilename(t *testing.T) {
 			name:     "Valid Format YYYYMMDD",
 			filename: "oauth_backup_20251102_040000.sql.gz",
 			want: &Metadata{
-				Service: "OAUTH",
-				Date:    "2025-11-02",
+				Service:   "OAUTH",
+				Date:      "2025-11-02",
+				Time:      "04:00:00",
+				Timestamp: time.Date(2025, 11, 2, 4, 0, 0, 0, time.UTC),
 			},
 			wantErr: false,
 		},
@@ -25,8 +28,10 @@ func TestParseFilename(t *testing.T) {
 			name:     "Valid Format YYYY-MM-DD",
 			filename: "keycloak_backup_2025-11-02_040000.sql",
 			want: &Metadata{
-				Service: "KEYCLOAK",
-				Date:    "2025-11-02",
+				Service:   "KEYCLOAK",
+				Date:      "2025-11-02",
+				Time:      "04:00:00",
+				Timestamp: time.Date(2025, 11, 2, 4, 0, 0, 0, time.UTC),
 			},
 			wantErr: false,
 		},
@@ -34,8 +39,10 @@ func TestParseFilename(t *testing.T) {
 			name:     "CamelCase Service Name",
 			filename: "MyAppService_backup_20251102_040000.zip",
 			want: &Metadata{
-				Service: "MY_APP_SERVICE",
-				Date:    "2025-11-02",
+				Service:   "MY_APP_SERVICE",
+				Date:      "2025-11-02",
+				Time:      "04:00:00",
+				Timestamp: time.Date(2025, 11, 2, 4, 0, 0, 0, time.UTC),
 			},
 			wantErr: false,
 		},
@@ -43,8 +50,20 @@ func TestParseFilename(t *testing.T) {
 			name:     "CamelCase Service Name 2",
 			filename: "OAuthBackup_backup_20251102_040000.zip",
 			want: &Metadata{
-				Service: "O_AUTH_BACKUP", // Based on current implementation logic of insert underscore before Upper
-				Date:    "2025-11-02",
+				Service:   "O_AUTH_BACKUP", // A single capital followed by a word is a one-letter acronym
+				Date:      "2025-11-02",
+				Time:      "04:00:00",
+				Timestamp: time.Date(2025, 11, 2, 4, 0, 0, 0, time.UTC),
+			},
+			wantErr: false,
+		},
+		{
+			name:     "Without Time Component",
+			filename: "keycloak_backup_20251102_full.sql",
+			want: &Metadata{
+				Service:   "KEYCLOAK",
+				Date:      "2025-11-02",
+				Timestamp: time.Date(2025, 11, 2, 0, 0, 0, 0, time.UTC),
 			},
 			wantErr: false,
 		},
@@ -54,6 +73,18 @@ func TestParseFilename(t *testing.T) {
 			want:     nil,
 			wantErr:  true,
 		},
+		{
+			name:     "Impossible Date",
+			filename: "oauth_backup_20251399_040000.sql.gz",
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "Impossible Time",
+			filename: "oauth_backup_20251102_256000.sql.gz",
+			want:     nil,
+			wantErr:  true,
+		},
 		{
 			name:     "Missing Date",
 			filename: "service_backup_no_date.zip",
@@ -84,7 +115,7 @@ func TestCamelToSnakeCase(t *testing.T) {
 		{"myApp", "MY_APP"},
 		{"simple", "SIMPLE"},
 		{"OAuth", "O_AUTH"},
-		{"APIClient", "A_P_I_CLIENT"},
+		{"APIClient", "API_CLIENT"},
 	}
 
 	for _, tt := range tests {
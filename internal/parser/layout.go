@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLayout is the folder hierarchy smart organize creates without --organize-layout
+const DefaultLayout = "{service}/{date}"
+
+// layoutFields renders each placeholder a layout may use. ok is false when
+// the metadata has no value for it.
+var layoutFields = map[string]func(meta *Metadata) (value string, ok bool){
+	"service": func(m *Metadata) (string, bool) { return m.Service, m.Service != "" },
+	"env":     func(m *Metadata) (string, bool) { return m.Env, m.Env != "" },
+	"host":    func(m *Metadata) (string, bool) { return m.Host, m.Host != "" },
+	"date":    func(m *Metadata) (string, bool) { return m.Date, m.Date != "" },
+	"time":    func(m *Metadata) (string, bool) { return m.Time, m.Time != "" },
+	"yyyy":    dateField("2006"),
+	"MM":      dateField("01"),
+	"dd":      dateField("02"),
+	"HH":      timeField("15"),
+	"mm":      timeField("04"),
+	"ss":      timeField("05"),
+}
+
+// dateField renders part of the date with a Go time layout
+func dateField(layout string) func(*Metadata) (string, bool) {
+	return func(m *Metadata) (string, bool) {
+		t, err := time.Parse("2006-01-02", m.Date)
+		if err != nil {
+			return "", false
+		}
+		return t.Format(layout), true
+	}
+}
+
+// timeField renders part of the time with a Go time layout
+func timeField(layout string) func(*Metadata) (string, bool) {
+	return func(m *Metadata) (string, bool) {
+		t, err := time.Parse("15:04:05", m.Time)
+		if err != nil {
+			return "", false
+		}
+		return t.Format(layout), true
+	}
+}
+
+// layoutPart is either a placeholder or literal text of a folder name
+type layoutPart struct {
+	field   string
+	literal string
+}
+
+// Layout is a compiled folder hierarchy template such as {env}/{service}/{yyyy}/{MM}/{dd}.
+// Each slash-separated part is a folder level.
+type Layout struct {
+	source   string
+	levels   [][]layoutPart
+	defaults map[string]string
+}
+
+// ParseLayout compiles a layout template. defaults gives the value of
+// placeholders the filename does not provide, e.g. env=shared; without a
+// default, a missing placeholder is an error.
+func ParseLayout(template string, defaults map[string]string) (*Layout, error) {
+	for name := range defaults {
+		if _, ok := layoutFields[name]; !ok {
+			return nil, fmt.Errorf("unknown layout field '%s' in defaults (valid: %s)", name, layoutFieldNames())
+		}
+	}
+
+	l := &Layout{source: template, defaults: defaults}
+	for _, level := range strings.Split(template, "/") {
+		var parts []layoutPart
+		rest := level
+		for rest != "" {
+			start := strings.IndexByte(rest, '{')
+			if start < 0 {
+				parts = append(parts, layoutPart{literal: rest})
+				break
+			}
+			if start > 0 {
+				parts = append(parts, layoutPart{literal: rest[:start]})
+			}
+
+			end := strings.IndexByte(rest[start:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '{' in layout '%s'", template)
+			}
+			name := rest[start+1 : start+end]
+			if _, ok := layoutFields[name]; !ok {
+				return nil, fmt.Errorf("unknown field '{%s}' in layout '%s' (valid: %s)", name, template, layoutFieldNames())
+			}
+			parts = append(parts, layoutPart{field: name})
+			rest = rest[start+end+1:]
+		}
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("empty folder level in layout '%s'", template)
+		}
+		l.levels = append(l.levels, parts)
+	}
+	return l, nil
+}
+
+// String returns the layout template as given by the user
+func (l *Layout) String() string {
+	return l.source
+}
+
+// Folders returns the folder names, from the top level down, for a file with the given metadata
+func (l *Layout) Folders(meta *Metadata) ([]string, error) {
+	var folders []string
+	for _, level := range l.levels {
+		var name strings.Builder
+		for _, part := range level {
+			if part.field == "" {
+				name.WriteString(part.literal)
+				continue
+			}
+
+			value, ok := layoutFields[part.field](meta)
+			if !ok {
+				if value, ok = l.defaults[part.field]; !ok {
+					return nil, fmt.Errorf("filename has no {%s} for layout '%s' and no default is set", part.field, l.source)
+				}
+			}
+			name.WriteString(value)
+		}
+		if name.Len() == 0 {
+			return nil, fmt.Errorf("layout '%s' produces an empty folder name", l.source)
+		}
+		folders = append(folders, name.String())
+	}
+	return folders, nil
+}
+
+// layoutFieldNames lists the placeholders for error messages
+func layoutFieldNames() string {
+	names := make([]string, 0, len(layoutFields))
+	for name := range layoutFields {
+		names = append(names, "{"+name+"}")
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
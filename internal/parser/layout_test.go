@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLayoutFolders(t *testing.T) {
+	meta := &Metadata{Service: "KEYCLOAK", Date: "2025-12-24", Time: "08:30:15", Env: "prod"}
+
+	tests := []struct {
+		name     string
+		layout   string
+		defaults map[string]string
+		meta     *Metadata
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:   "Default layout",
+			layout: DefaultLayout,
+			meta:   meta,
+			want:   []string{"KEYCLOAK", "2025-12-24"},
+		},
+		{
+			name:   "Env and date parts",
+			layout: "{env}/{service}/{yyyy}/{MM}/{dd}",
+			meta:   meta,
+			want:   []string{"prod", "KEYCLOAK", "2025", "12", "24"},
+		},
+		{
+			name:   "Hourly folders with literals",
+			layout: "{service}/{date}/{HH}h{mm}",
+			meta:   meta,
+			want:   []string{"KEYCLOAK", "2025-12-24", "08h30"},
+		},
+		{
+			name:     "Missing field uses the default",
+			layout:   "{host}/{service}",
+			defaults: map[string]string{"host": "unknown-host"},
+			meta:     meta,
+			want:     []string{"unknown-host", "KEYCLOAK"},
+		},
+		{
+			name:    "Missing field without default",
+			layout:  "{host}/{service}",
+			meta:    meta,
+			wantErr: true,
+		},
+		{
+			name:    "Missing time",
+			layout:  "{service}/{HH}",
+			meta:    &Metadata{Service: "KEYCLOAK", Date: "2025-12-24"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := ParseLayout(tt.layout, tt.defaults)
+			if err != nil {
+				t.Fatalf("ParseLayout() error = %v", err)
+			}
+			got, err := l.Folders(tt.meta)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Folders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Folders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLayoutInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   string
+		defaults map[string]string
+	}{
+		{"Unknown field", "{service}/{week}", nil},
+		{"Unterminated brace", "{service}/{date", nil},
+		{"Empty level", "{service}//{date}", nil},
+		{"Unknown default", "{service}", map[string]string{"region": "eu"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseLayout(tt.layout, tt.defaults); err == nil {
+				t.Errorf("ParseLayout(%q) expected an error", tt.layout)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Named groups a rule may capture; service and date are required
+const (
+	groupService = "service"
+	groupDate    = "date"
+	groupTime    = "time"
+	groupEnv     = "env"
+	groupHost    = "host"
+)
+
+// Rule is a user-defined filename pattern
+type Rule struct {
+	// Name identifies the rule in messages and in "uploader parse"
+	Name string `json:"name"`
+	// Pattern is a regular expression with the named groups service and date,
+	// and optionally time, env and host
+	Pattern string `json:"pattern"`
+	// DateLayout is the Go time layout of the date group, e.g. 02.01.2006.
+	// When empty, the date must be YYYYMMDD or YYYY-MM-DD.
+	DateLayout string `json:"date_layout,omitempty"`
+	// TimeLayout is the Go time layout of the time group. When empty, the time must be HHMMSS.
+	TimeLayout string `json:"time_layout,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// DefaultRule is the built-in [service]_backup_[date]_[time] convention, tried after user-defined rules
+var DefaultRule = Rule{
+	Name:    "default",
+	Pattern: `^(?P<service>[a-zA-Z0-9]+)_backup_(?P<date>\d{8}|\d{4}-\d{2}-\d{2})_(?P<time>\d{6})?.*`,
+}
+
+// Layouts of the date and time groups of rules that do not set one
+var defaultDateLayouts = []string{"20060102", "2006-01-02"}
+
+const defaultTimeLayout = "150405"
+
+// What smart organize does with files no rule matches
+const (
+	// FallbackNone uploads them to the current folder
+	FallbackNone = "none"
+	// FallbackUnsorted uploads them to a folder named by RuleSet.UnsortedFolder
+	FallbackUnsorted = "unsorted"
+	// FallbackModTime uploads them to a yyyy-MM-dd folder named after their modification time
+	FallbackModTime = "mtime"
+	// FallbackReject fails them without uploading
+	FallbackReject = "reject"
+)
+
+// DefaultUnsortedFolder is the folder of FallbackUnsorted when the rule set does not name one
+const DefaultUnsortedFolder = "_unsorted"
+
+// RuleSet is the format of the parse rules file
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+	// Fallback is what smart organize does with files no rule matches, FallbackNone when empty
+	Fallback       string `json:"fallback,omitempty"`
+	UnsortedFolder string `json:"unsorted_folder,omitempty"`
+	// ServiceAliases maps the service names of several producers to one canonical folder
+	ServiceAliases map[string]string `json:"service_aliases,omitempty"`
+}
+
+// ValidateFallback checks that fallback is one of the Fallback constants (or empty)
+func ValidateFallback(fallback string) error {
+	switch fallback {
+	case "", FallbackNone, FallbackUnsorted, FallbackModTime, FallbackReject:
+		return nil
+	}
+	return fmt.Errorf("fallback must be '%s', '%s', '%s' or '%s'", FallbackNone, FallbackUnsorted, FallbackModTime, FallbackReject)
+}
+
+// Options configures how a Parser reports the metadata it extracts
+type Options struct {
+	// Location is the zone dates and times are reported in (UTC when nil)
+	Location *time.Location
+	// ServiceCase is a Case constant, CaseUpperSnake when empty
+	ServiceCase string
+	// ServiceAliases maps service names, case-insensitively, to the canonical
+	// folder name used as is, e.g. kc -> KEYCLOAK
+	ServiceAliases map[string]string
+}
+
+// Parser extracts metadata from filenames with a list of rules, tried in order
+type Parser struct {
+	rules []Rule
+	opts  Options
+}
+
+// NewParser compiles rules, followed by DefaultRule
+func NewParser(rules []Rule, opts Options) (*Parser, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if err := ValidateServiceCase(opts.ServiceCase); err != nil {
+		return nil, err
+	}
+
+	// Aliases match case-insensitively, so they are looked up by lower-case name
+	aliases := make(map[string]string, len(opts.ServiceAliases))
+	for alias, canonical := range opts.ServiceAliases {
+		aliases[strings.ToLower(alias)] = canonical
+	}
+	opts.ServiceAliases = aliases
+
+	p := &Parser{opts: opts}
+	for _, rule := range append(append([]Rule{}, rules...), DefaultRule) {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': invalid pattern: %v", rule.Name, err)
+		}
+		if re.SubexpIndex(groupService) < 0 || re.SubexpIndex(groupDate) < 0 {
+			return nil, fmt.Errorf("rule '%s': pattern must have the named groups (?P<service>...) and (?P<date>...)", rule.Name)
+		}
+		rule.re = re
+		p.rules = append(p.rules, rule)
+	}
+	return p, nil
+}
+
+// LoadRuleSet reads a JSON rules file
+func LoadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read parse rules: %v", err)
+	}
+
+	set := &RuleSet{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("unable to parse rules file '%s': %v", path, err)
+	}
+	if err := ValidateFallback(set.Fallback); err != nil {
+		return nil, fmt.Errorf("rules file '%s': %v", path, err)
+	}
+	return set, nil
+}
+
+// Location returns the zone dates and times are reported in
+func (p *Parser) Location() *time.Location {
+	return p.opts.Location
+}
+
+// defaultParser only knows DefaultRule
+var defaultParser, _ = NewParser(nil, Options{})
+
+// Parse extracts metadata from filename with the first matching rule
+func (p *Parser) Parse(filename string) (*Metadata, error) {
+	meta, _, err := p.Match(filename)
+	return meta, err
+}
+
+// Match is like Parse and also returns the rule that matched
+func (p *Parser) Match(filename string) (*Metadata, *Rule, error) {
+	for i := range p.rules {
+		meta, ok := p.rules[i].parse(filename, p.opts)
+		if ok {
+			return meta, &p.rules[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("filename '%s' does not match any parse rule", filename)
+}
+
+// parse applies the rule to filename. A match whose date or time does not
+// fit the rule layouts is not a match. Times in names are UTC and are
+// converted to opts.Location, which may move the backup to the previous or next date.
+func (r *Rule) parse(filename string, opts Options) (*Metadata, bool) {
+	matches := r.re.FindStringSubmatch(filename)
+	if matches == nil {
+		return nil, false
+	}
+
+	groups := make(map[string]string)
+	for i, name := range r.re.SubexpNames() {
+		if i != 0 && name != "" {
+			groups[name] = matches[i]
+		}
+	}
+
+	date, ok := r.parseDate(groups[groupDate])
+	if !ok {
+		return nil, false
+	}
+
+	meta := &Metadata{
+		Service: formatService(groups[groupService], opts.ServiceCase, opts.ServiceAliases),
+		Env:     groups[groupEnv],
+		Host:    groups[groupHost],
+	}
+
+	// Without a time there is no instant to convert, so the date is kept as is
+	if groups[groupTime] == "" {
+		meta.Timestamp = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, opts.Location)
+		meta.Date = meta.Timestamp.Format("2006-01-02")
+		return meta, true
+	}
+
+	clock, ok := r.parseClock(groups[groupTime])
+	if !ok {
+		return nil, false
+	}
+	stamped := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, time.UTC)
+	meta.Timestamp = stamped.In(opts.Location)
+	meta.Date = meta.Timestamp.Format("2006-01-02")
+	meta.Time = meta.Timestamp.Format("15:04:05")
+	return meta, true
+}
+
+// parseDate parses the date group with the rule layout, YYYYMMDD or YYYY-MM-DD by default.
+// Impossible dates such as 2025-13-99 are rejected.
+func (r *Rule) parseDate(value string) (time.Time, bool) {
+	layouts := defaultDateLayouts
+	if r.DateLayout != "" {
+		layouts = []string{r.DateLayout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseClock parses the time group with the rule layout, HHMMSS by default
+func (r *Rule) parseClock(value string) (time.Time, bool) {
+	layout := r.TimeLayout
+	if layout == "" {
+		layout = defaultTimeLayout
+	}
+	t, err := time.Parse(layout, value)
+	return t, err == nil
+}
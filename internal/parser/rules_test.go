@@ -0,0 +1,252 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func utc(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+func TestParserRules(t *testing.T) {
+	rules := []Rule{
+		{
+			Name:       "env",
+			Pattern:    `^(?P<service>[a-z]+)-(?P<env>prod|staging)_(?P<date>\d{4}-\d{2}-\d{2})\.dump$`,
+			DateLayout: "2006-01-02",
+		},
+		{
+			Name:       "prefix",
+			Pattern:    `^backup-(?P<service>[a-z]+)-(?P<date>\d{8})(?:-(?P<time>\d{4}))?\.tar$`,
+			DateLayout: "20060102",
+			TimeLayout: "1504",
+		},
+		{
+			Name:       "host",
+			Pattern:    `^(?P<host>[a-z0-9]+)\.(?P<service>[a-z]+)\.(?P<date>\d{2}\.\d{2}\.\d{4})`,
+			DateLayout: "02.01.2006",
+		},
+	}
+
+	p, err := NewParser(rules, Options{})
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		want     *Metadata
+		wantRule string
+	}{
+		{
+			name:     "Env group",
+			filename: "keycloak-prod_2025-12-24.dump",
+			want:     &Metadata{Service: "KEYCLOAK", Date: "2025-12-24", Env: "prod", Timestamp: utc(2025, 12, 24, 0, 0, 0)},
+			wantRule: "env",
+		},
+		{
+			name:     "Custom time layout",
+			filename: "backup-mysql-20251224-0830.tar",
+			want:     &Metadata{Service: "MYSQL", Date: "2025-12-24", Time: "08:30:00", Timestamp: utc(2025, 12, 24, 8, 30, 0)},
+			wantRule: "prefix",
+		},
+		{
+			name:     "Without optional time",
+			filename: "backup-mysql-20251224.tar",
+			want:     &Metadata{Service: "MYSQL", Date: "2025-12-24", Timestamp: utc(2025, 12, 24, 0, 0, 0)},
+			wantRule: "prefix",
+		},
+		{
+			name:     "Host group and day-first layout",
+			filename: "db01.postgres.24.12.2025.sql.gz",
+			want:     &Metadata{Service: "POSTGRES", Date: "2025-12-24", Host: "db01", Timestamp: utc(2025, 12, 24, 0, 0, 0)},
+			wantRule: "host",
+		},
+		{
+			name:     "Falls back to the default rule",
+			filename: "oauth_backup_20251102_040000.sql.gz",
+			want:     &Metadata{Service: "OAUTH", Date: "2025-11-02", Time: "04:00:00", Timestamp: utc(2025, 11, 2, 4, 0, 0)},
+			wantRule: "default",
+		},
+		{
+			name:     "Date not matching the layout",
+			filename: "backup-mysql-20251324.tar",
+		},
+		{
+			name:     "No rule matches",
+			filename: "random_file.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rule, err := p.Match(tt.filename)
+			if (err != nil) != (tt.want == nil) {
+				t.Fatalf("Match() error = %v, want match %v", err, tt.want != nil)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %+v, want %+v", got, tt.want)
+			}
+			if rule != nil && rule.Name != tt.wantRule {
+				t.Errorf("Match() rule = %q, want %q", rule.Name, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestParserRulesOrder(t *testing.T) {
+	// Both rules match, the first listed wins
+	p, err := NewParser([]Rule{
+		{Name: "first", Pattern: `^(?P<service>[a-z]+)_(?P<date>\d{8})`},
+		{Name: "second", Pattern: `^(?P<service>[a-z]+)_(?P<date>\d{8})_(?P<time>\d{6})`},
+	}, Options{})
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+
+	_, rule, err := p.Match("app_20251224_120000.zip")
+	if err != nil {
+		t.Fatalf("Match() error = %v", err)
+	}
+	if rule.Name != "first" {
+		t.Errorf("Match() rule = %q, want %q", rule.Name, "first")
+	}
+}
+
+func TestParserTimezone(t *testing.T) {
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		filename string
+		wantDate string
+		wantTime string
+	}{
+		{"Just after midnight UTC moves to the previous day", saoPaulo, "db_backup_20251224_013000.sql", "2025-12-23", "22:30:00"},
+		{"Late UTC moves to the next day", tokyo, "db_backup_20251224_200000.sql", "2025-12-25", "05:00:00"},
+		{"Without time the date is kept", saoPaulo, "db_backup_20251224_full.sql", "2025-12-24", ""},
+		{"UTC by default", nil, "db_backup_20251224_013000.sql", "2025-12-24", "01:30:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewParser(nil, Options{Location: tt.loc})
+			if err != nil {
+				t.Fatalf("NewParser() error = %v", err)
+			}
+			meta, err := p.Parse(tt.filename)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if meta.Date != tt.wantDate || meta.Time != tt.wantTime {
+				t.Errorf("Parse() = %s %s, want %s %s", meta.Date, meta.Time, tt.wantDate, tt.wantTime)
+			}
+			if meta.Timestamp.Format("2006-01-02") != tt.wantDate {
+				t.Errorf("Parse() timestamp = %v, want date %s", meta.Timestamp, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestParserServiceOptions(t *testing.T) {
+	p, err := NewParser(nil, Options{
+		ServiceCase:    CaseKebab,
+		ServiceAliases: map[string]string{"oauth": "OAUTH"},
+	})
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"myAppService_backup_20251102_040000.zip", "my-app-service"},
+		{"OAuth_backup_20251102_040000.zip", "OAUTH"},
+		{"oauth_backup_20251102_040000.zip", "OAUTH"},
+	}
+	for _, tt := range tests {
+		meta, err := p.Parse(tt.filename)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", tt.filename, err)
+		}
+		if meta.Service != tt.want {
+			t.Errorf("Parse(%s) service = %q, want %q", tt.filename, meta.Service, tt.want)
+		}
+	}
+
+	if _, err := NewParser(nil, Options{ServiceCase: "camel"}); err == nil {
+		t.Errorf("NewParser() expected an error for an unknown service case")
+	}
+}
+
+func TestNewParserInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"Invalid regex", Rule{Name: "bad", Pattern: `(?P<service>[a-z+`}},
+		{"Missing service group", Rule{Name: "nosvc", Pattern: `^(?P<date>\d{8})`}},
+		{"Missing date group", Rule{Name: "nodate", Pattern: `^(?P<service>[a-z]+)`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewParser([]Rule{tt.rule}, Options{}); err == nil {
+				t.Errorf("NewParser() expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadRuleSet(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "parse-rules.json")
+	content := `{"fallback": "unsorted", "unsorted_folder": "_stray", "service_aliases": {"kc": "KEYCLOAK"}, "rules": [{"name": "keycloak", "pattern": "^(?P<service>keycloak)-(?P<env>[a-z]+)_(?P<date>\\d{4}-\\d{2}-\\d{2})", "date_layout": "2006-01-02"}]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := LoadRuleSet(path)
+	if err != nil {
+		t.Fatalf("LoadRuleSet() error = %v", err)
+	}
+	want := &RuleSet{
+		Rules: []Rule{{
+			Name:       "keycloak",
+			Pattern:    `^(?P<service>keycloak)-(?P<env>[a-z]+)_(?P<date>\d{4}-\d{2}-\d{2})`,
+			DateLayout: "2006-01-02",
+		}},
+		Fallback:       FallbackUnsorted,
+		UnsortedFolder: "_stray",
+		ServiceAliases: map[string]string{"kc": "KEYCLOAK"},
+	}
+	if !reflect.DeepEqual(set, want) {
+		t.Errorf("LoadRuleSet() = %+v, want %+v", set, want)
+	}
+
+	if _, err := LoadRuleSet(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("LoadRuleSet() expected an error for a missing file")
+	}
+
+	invalid := filepath.Join(dir, "invalid-fallback.json")
+	if err := os.WriteFile(invalid, []byte(`{"fallback": "guess", "rules": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRuleSet(invalid); err == nil {
+		t.Errorf("LoadRuleSet() expected an error for an unknown fallback")
+	}
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Service name cases of smart organize folders
+const (
+	// CaseUpperSnake turns myAppService into MY_APP_SERVICE (default)
+	CaseUpperSnake = "upper-snake"
+	// CaseLowerSnake turns myAppService into my_app_service
+	CaseLowerSnake = "lower-snake"
+	// CaseKebab turns myAppService into my-app-service
+	CaseKebab = "kebab"
+	// CasePreserve keeps the name as it appears in the filename
+	CasePreserve = "preserve"
+)
+
+// ValidateServiceCase checks that serviceCase is one of the Case constants (or empty)
+func ValidateServiceCase(serviceCase string) error {
+	switch serviceCase {
+	case "", CaseUpperSnake, CaseLowerSnake, CaseKebab, CasePreserve:
+		return nil
+	}
+	return fmt.Errorf("service case must be '%s', '%s', '%s' or '%s'", CaseUpperSnake, CaseLowerSnake, CaseKebab, CasePreserve)
+}
+
+// formatService returns the folder name of a service captured from a filename.
+// An alias is used as is, otherwise the name is converted to serviceCase.
+// Alias keys must be lower case, see NewParser.
+func formatService(name string, serviceCase string, aliases map[string]string) string {
+	if canonical, ok := aliases[strings.ToLower(name)]; ok {
+		return canonical
+	}
+
+	switch serviceCase {
+	case CasePreserve:
+		return name
+	case CaseLowerSnake:
+		return strings.ToLower(strings.Join(splitWords(name), "_"))
+	case CaseKebab:
+		return strings.ToLower(strings.Join(splitWords(name), "-"))
+	default:
+		return camelToSnakeCase(name)
+	}
+}
+
+// splitWords splits a CamelCase, snake_case or kebab-case name into words.
+// Acronym runs stay together: APIClient is API, Client and myHTTPServer is my, HTTP, Server.
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || r == ' ' {
+			flush()
+			continue
+		}
+
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// A new word starts after a lowercase letter or digit (myApp), or at
+			// the last capital of an acronym followed by lowercase (APIClient)
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatService(t *testing.T) {
+	aliases := map[string]string{"oauth": "OAUTH", "kc": "KEYCLOAK"}
+
+	tests := []struct {
+		name        string
+		input       string
+		serviceCase string
+		want        string
+	}{
+		{"Upper snake by default", "myAppService", "", "MY_APP_SERVICE"},
+		{"Upper snake acronym run", "APIClient", CaseUpperSnake, "API_CLIENT"},
+		{"Lower snake", "myHTTPServer", CaseLowerSnake, "my_http_server"},
+		{"Kebab", "myAppService", CaseKebab, "my-app-service"},
+		{"Kebab from snake case", "my_app_service", CaseKebab, "my-app-service"},
+		{"Digits end a word", "mysql8Prod", CaseLowerSnake, "mysql8_prod"},
+		{"Preserve", "myAppService", CasePreserve, "myAppService"},
+		{"Alias ignores case", "OAuth", CaseUpperSnake, "OAUTH"},
+		{"Alias is used as is", "kc", CaseKebab, "KEYCLOAK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatService(tt.input, tt.serviceCase, aliases); got != tt.want {
+				t.Errorf("formatService(%q, %q) = %q, want %q", tt.input, tt.serviceCase, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"myAppService", []string{"my", "App", "Service"}},
+		{"OAuthBackup", []string{"O", "Auth", "Backup"}},
+		{"APIClient", []string{"API", "Client"}},
+		{"S3Backup", []string{"S3", "Backup"}},
+		{"keycloak-prod_db", []string{"keycloak", "prod", "db"}},
+		{"HTTP", []string{"HTTP"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitWords(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// DriveService defines the interface for Drive operations needed by the trash purge
+type DriveService interface {
+	ListTrashed(ctx context.Context) ([]*drive.File, error)
+	DeleteFile(ctx context.Context, fileID string) error
+}
+
+// Options configures a PurgeService
+type Options struct {
+	// OlderThan only purges items trashed more than this long ago; zero purges them regardless of age
+	OlderThan time.Duration
+	// Trashed maps the IDs of the items the uploader trashed, from the cleanup
+	// run logs, to when they were trashed
+	Trashed map[string]time.Time
+	// All purges every item in the account's trash, not only those in Trashed
+	All bool
+}
+
+// PurgeService permanently deletes items from the Drive trash
+type PurgeService struct {
+	driveService DriveService
+	olderThan    time.Duration
+	trashed      map[string]time.Time
+	all          bool
+	now          func() time.Time
+}
+
+// NewPurgeService creates a new purge service
+func NewPurgeService(driveService DriveService, opts Options) *PurgeService {
+	return &PurgeService{
+		driveService: driveService,
+		olderThan:    opts.OlderThan,
+		trashed:      opts.Trashed,
+		all:          opts.All,
+		now:          time.Now,
+	}
+}
+
+// Candidates returns the trashed items that Purge would delete: those the
+// uploader trashed (every item with All), trashed more than OlderThan ago
+func (p *PurgeService) Candidates(ctx context.Context) ([]*drive.File, error) {
+	files, err := p.driveService.ListTrashed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := p.now().Add(-p.olderThan)
+	var candidates []*drive.File
+	for _, f := range files {
+		if _, ok := p.trashed[f.Id]; !ok && !p.all {
+			continue
+		}
+
+		if p.olderThan > 0 {
+			trashedAt, ok := p.trashedTime(f)
+			if !ok {
+				log.Printf("Skipping '%s': unable to determine when it was trashed", f.Name)
+				continue
+			}
+			if !trashedAt.Before(cutoff) {
+				continue
+			}
+		}
+		candidates = append(candidates, f)
+	}
+
+	return candidates, nil
+}
+
+// Purge permanently deletes the given items and returns the names of those deleted.
+// Items that fail to delete are logged and skipped.
+func (p *PurgeService) Purge(ctx context.Context, files []*drive.File) ([]string, error) {
+	var purged []string
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		log.Printf("Permanently deleting from trash: %s", f.Name)
+		if err := p.driveService.DeleteFile(ctx, f.Id); err != nil {
+			log.Printf("Warning: Failed to delete '%s': %v", f.Name, err)
+			continue
+		}
+		purged = append(purged, f.Name)
+	}
+
+	return purged, nil
+}
+
+// trashedTime returns when an item was trashed. Drive only reports trashedTime
+// for shared drive items, so for My Drive it comes from the cleanup run logs.
+// modifiedTime is not a substitute: a folder trashed today may hold old files.
+func (p *PurgeService) trashedTime(f *drive.File) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, f.TrashedTime); err == nil {
+		return t, true
+	}
+	t, ok := p.trashed[f.Id]
+	return t, ok
+}
+
+// Describe returns a one-line description of a trashed item for confirmation prompts
+func (p *PurgeService) Describe(f *drive.File) string {
+	if t, ok := p.trashedTime(f); ok {
+		return fmt.Sprintf("%s (trashed: %s)", f.Name, t.Format("2006-01-02"))
+	}
+	return f.Name
+}
@@ -0,0 +1,103 @@
+package trash
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+type fakeDriveService struct {
+	trashed []*drive.File
+	deleted []string
+}
+
+func (f *fakeDriveService) ListTrashed(ctx context.Context) ([]*drive.File, error) {
+	return f.trashed, nil
+}
+
+func (f *fakeDriveService) DeleteFile(ctx context.Context, fileID string) error {
+	f.deleted = append(f.deleted, fileID)
+	return nil
+}
+
+func TestPurgeCandidates(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+
+	trashed := []*drive.File{
+		// Shared drive items report when they were trashed
+		{Id: "old", Name: "old", TrashedTime: "2025-01-01T00:00:00Z"},
+		{Id: "new", Name: "new", TrashedTime: "2025-06-09T00:00:00Z"},
+		// My Drive items do not: a folder cleanup trashed yesterday may hold old files
+		{Id: "recent-my-drive", Name: "recent-my-drive", ModifiedTime: "2025-02-01T00:00:00Z"},
+		{Id: "old-my-drive", Name: "old-my-drive", ModifiedTime: "2025-02-01T00:00:00Z"},
+		// Trashed by someone else
+		{Id: "foreign", Name: "foreign", ModifiedTime: "2025-02-01T00:00:00Z"},
+	}
+	// From the cleanup run logs
+	logged := map[string]time.Time{
+		"old":             time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		"new":             time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC),
+		"recent-my-drive": time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC),
+		"old-my-drive":    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "Logged items older than",
+			opts: Options{OlderThan: 30 * day, Trashed: logged},
+			want: []string{"old", "old-my-drive"},
+		},
+		{
+			name: "All logged items",
+			opts: Options{Trashed: logged},
+			want: []string{"old", "new", "recent-my-drive", "old-my-drive"},
+		},
+		{
+			name: "All items older than skips unknown trash dates",
+			opts: Options{OlderThan: 30 * day, All: true},
+			want: []string{"old"},
+		},
+		{
+			name: "Whole trash",
+			opts: Options{All: true},
+			want: []string{"old", "new", "recent-my-drive", "old-my-drive", "foreign"},
+		},
+		{
+			name: "Nothing logged",
+			opts: Options{},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDriveService{trashed: trashed}
+			p := NewPurgeService(fake, tt.opts)
+			p.now = func() time.Time { return now }
+
+			candidates, err := p.Candidates(context.Background())
+			if err != nil {
+				t.Fatalf("Candidates() error = %v", err)
+			}
+
+			purged, err := p.Purge(context.Background(), candidates)
+			if err != nil {
+				t.Fatalf("Purge() error = %v", err)
+			}
+			if !reflect.DeepEqual(purged, tt.want) {
+				t.Errorf("Purge() = %v, want %v", purged, tt.want)
+			}
+			if !reflect.DeepEqual(fake.deleted, tt.want) {
+				t.Errorf("deleted = %v, want %v", fake.deleted, tt.want)
+			}
+		})
+	}
+}